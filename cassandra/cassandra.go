@@ -0,0 +1,146 @@
+package cassandra
+
+import (
+	"fmt"
+	"github.com/gocql/gocql"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"testing"
+	"time"
+)
+
+const (
+	defaultCassandraImage = "cassandra"
+	defaultCassandraTag   = "4.1"
+	defaultCassandraPort  = "9042/tcp"
+	// A fresh node bootstraps its gossip state and system keyspaces before it accepts CQL.
+	defaultCassandraMaxWait = 3 * time.Minute
+)
+
+// Run starts a Cassandra Docker container using the default settings and returns a connected
+// *gocql.Session along with a cleanup function. It uses the default Cassandra image ("cassandra")
+// with tag "4.1". For more customization, use RunWithOptions.
+func Run(t testing.TB) (*gocql.Session, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts a Cassandra Docker container using Docker and returns a connected
+// *gocql.Session along with a cleanup function. It applies the default settings:
+//   - Repository: "cassandra"
+//   - Tag: "4.1"
+//   - Environment: MAX_HEAP_SIZE=512M, HEAP_NEWSIZE=128M
+//
+// The session is not bound to a keyspace, so queries should use fully qualified table names.
+// Because the node advertises its container address, initial host lookup is disabled and the
+// session always talks to the published port. ScyllaDB can be used by overriding the repository.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*gocql.Session, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+	pool.MaxWait = defaultCassandraMaxWait
+
+	// Set default run options for Cassandra
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultCassandraImage,
+		Tag:        defaultCassandraTag,
+		Env: []string{
+			"MAX_HEAP_SIZE=512M",
+			"HEAP_NEWSIZE=128M",
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start cassandra container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultCassandraPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the cassandra container")
+	}
+	t.Logf("cassandra container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure cassandra container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	var session *gocql.Session
+	if err = settings.Retry(pool, func() error {
+		cluster := gocql.NewCluster(actualPort)
+		cluster.DisableInitialHostLookup = true
+		cluster.Consistency = gocql.One
+		cluster.ConnectTimeout = 10 * time.Second
+		cluster.Timeout = 10 * time.Second
+
+		var err error
+		session, err = cluster.CreateSession()
+		if err != nil {
+			return err
+		}
+		if err := session.Query("SELECT release_version FROM system.local").Exec(); err != nil {
+			session.Close()
+			return err
+		}
+		return nil
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to cassandra: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		session.Close()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove cassandra container: %s", err)
+		}
+	}
+
+	return session, cleanup
+}
+
+// PrepKeyspace creates a keyspace with SimpleStrategy and the given replication factor,
+// if it does not already exist. If the statement fails, it returns an error.
+func PrepKeyspace(t testing.TB, session *gocql.Session, name string, replication int) error {
+	t.Helper()
+
+	stmt := fmt.Sprintf(
+		"CREATE KEYSPACE IF NOT EXISTS %s WITH replication = {'class': 'SimpleStrategy', 'replication_factor': %d}",
+		name, replication,
+	)
+	if err := session.Query(stmt).Exec(); err != nil {
+		return fmt.Errorf("failed to create keyspace '%s': %w", name, err)
+	}
+	return nil
+}
+
+// PrepSchema executes the given CQL statements in order, e.g. CREATE TABLE or CREATE INDEX.
+// CQL does not support several statements in one request, so each statement must be passed separately.
+// If any statement fails, it returns an error.
+func PrepSchema(t testing.TB, session *gocql.Session, stmts ...string) error {
+	t.Helper()
+
+	for _, stmt := range stmts {
+		if err := session.Query(stmt).Exec(); err != nil {
+			return fmt.Errorf("failed to execute schema statement: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package cassandra_test
+
+import (
+	"github.com/ory/dockertest/v3"
+	"github.com/vvatanabe/dockertestx/cassandra"
+	"testing"
+)
+
+// TestDefaultCassandra demonstrates creating a keyspace and table and inserting a row.
+func TestDefaultCassandra(t *testing.T) {
+	// Start a Cassandra container with default options.
+	session, cleanup := cassandra.Run(t)
+	defer cleanup()
+
+	if err := cassandra.PrepKeyspace(t, session, "shop", 1); err != nil {
+		t.Fatalf("PrepKeyspace failed: %v", err)
+	}
+	if err := cassandra.PrepSchema(t, session,
+		`CREATE TABLE IF NOT EXISTS shop.users (id int PRIMARY KEY, name text)`,
+	); err != nil {
+		t.Fatalf("PrepSchema failed: %v", err)
+	}
+
+	// Insert a row
+	if err := session.Query(`INSERT INTO shop.users (id, name) VALUES (?, ?)`, 1, "Alice").Exec(); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+
+	// Read it back
+	var name string
+	if err := session.Query(`SELECT name FROM shop.users WHERE id = ?`, 1).Scan(&name); err != nil {
+		t.Fatalf("failed to retrieve row: %v", err)
+	}
+	if name != "Alice" {
+		t.Errorf("expected name 'Alice', but got '%s'", name)
+	}
+}
+
+// TestCassandraWithCustomRunOptions demonstrates overriding default RunOptions.
+func TestCassandraWithCustomRunOptions(t *testing.T) {
+	// Custom RunOption to override the default tag
+	customTag := func(opts *dockertest.RunOptions) {
+		opts.Tag = "4.0"
+	}
+
+	session, cleanup := cassandra.RunWithOptions(t, []func(*dockertest.RunOptions){customTag})
+	defer cleanup()
+
+	var version string
+	if err := session.Query("SELECT release_version FROM system.local").Scan(&version); err != nil {
+		t.Fatalf("failed to query release version: %v", err)
+	}
+	if len(version) < 3 || version[:3] != "4.0" {
+		t.Errorf("expected release version 4.0.x, but got '%s'", version)
+	}
+}
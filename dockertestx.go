@@ -0,0 +1,173 @@
+// Package dockertestx provides building blocks shared by the service specific packages,
+// such as RunContainer for images that have no dedicated helper.
+package dockertestx
+
+import (
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"testing"
+)
+
+// RunContainerConfig describes an arbitrary container started by RunContainer.
+type RunContainerConfig struct {
+	// Repository is the image repository, e.g. "nginx".
+	Repository string
+	// Tag is the image tag. It defaults to "latest".
+	Tag string
+	// Env contains environment variables in the form "KEY=value".
+	Env []string
+	// Cmd overrides the image's default command when set.
+	Cmd []string
+	// ExposedPort is the container port to publish, e.g. "80/tcp".
+	ExposedPort string
+	// ReadyFunc is called with the published "host:port" until it returns nil.
+	// It is used as the body of the readiness retry. When nil, the container is considered
+	// ready as soon as it has been started.
+	ReadyFunc func(hostPort string) error
+	// RunOptions are applied to the dockertest.RunOptions built from the fields above.
+	RunOptions []func(*dockertest.RunOptions)
+	// HostOptions are applied to the Docker host configuration.
+	HostOptions []func(*docker.HostConfig)
+}
+
+// Container is a container started by RunContainer.
+type Container struct {
+	// HostPort is the "host:port" address the exposed port is published on.
+	HostPort string
+	// Resource is the underlying dockertest resource, for access to the container itself.
+	Resource *dockertest.Resource
+}
+
+// RunContainer starts a container from an arbitrary image described by cfg and returns it along
+// with a cleanup function. It takes care of the pool, the port mapping, the readiness retry and
+// the cleanup, so only the image and the readiness check need to be provided.
+func RunContainer(t testing.TB, cfg RunContainerConfig) (*Container, func()) {
+	t.Helper()
+
+	if cfg.Repository == "" {
+		t.Fatal("RunContainerConfig.Repository must not be empty")
+	}
+	if cfg.ExposedPort == "" {
+		t.Fatal("RunContainerConfig.ExposedPort must not be empty")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	runOpts := &dockertest.RunOptions{
+		Repository:   cfg.Repository,
+		Tag:          tag,
+		Env:          cfg.Env,
+		Cmd:          cfg.Cmd,
+		ExposedPorts: []string{cfg.ExposedPort},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range cfg.RunOptions {
+		opt(runOpts)
+	}
+
+	settings := internal.TakeSettings(runOpts)
+
+	resource, err := pool.RunWithOptions(runOpts, cfg.HostOptions...)
+	if err != nil {
+		t.Fatalf("failed to start %s container: %s", cfg.Repository, err)
+	}
+
+	hostPort := resource.GetHostPort(cfg.ExposedPort)
+	if hostPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatalf("no host port was assigned for the %s container", cfg.Repository)
+	}
+	t.Logf("%s container is running on host port '%s'", cfg.Repository, hostPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure %s container: %s", cfg.Repository, err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	if cfg.ReadyFunc != nil {
+		if err = settings.Retry(pool, func() error {
+			return cfg.ReadyFunc(hostPort)
+		}); err != nil {
+			logs.Dump()
+			_ = pool.Purge(resource)
+			t.Fatalf("%s container did not become ready: %s", cfg.Repository, err)
+		}
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove %s container: %s", cfg.Repository, err)
+		}
+	}
+
+	return &Container{HostPort: hostPort, Resource: resource}, cleanup
+}
+
+// restartTimeout is the number of seconds Docker waits for the container to stop before killing it.
+const restartTimeout = 10
+
+// Restart stops the container behind resource and starts it again, keeping its file system and
+// volumes, e.g. to test that data survives a restart. The resource is refreshed afterwards.
+// Docker may publish the container's ports on different host ports after the restart, so clients
+// should reconnect using resource.GetHostPort and wait until the service is ready again.
+// If the container cannot be restarted, it returns an error.
+func Restart(t testing.TB, resource *dockertest.Resource) error {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return fmt.Errorf("failed to connect to docker: %w", err)
+	}
+
+	id := resource.Container.ID
+	if err := pool.Client.RestartContainer(id, restartTimeout); err != nil {
+		return fmt.Errorf("failed to restart container %s: %w", id, err)
+	}
+
+	container, err := pool.Client.InspectContainer(id)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container %s: %w", id, err)
+	}
+	resource.Container = container
+	t.Logf("container %s was restarted", resource.Container.Name)
+	return nil
+}
+
+// ContainerLogs returns everything the container behind resource has written to stdout and stderr
+// so far, e.g. to assert that a migration ran. The resource can be obtained with the WithResource
+// RunOption. If the logs cannot be fetched, it returns an error.
+func ContainerLogs(t testing.TB, resource *dockertest.Resource) (string, error) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to docker: %w", err)
+	}
+
+	logs, err := internal.FetchLogs(pool, resource.Container.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch logs of container %s: %w", resource.Container.ID, err)
+	}
+	return logs, nil
+}
+
+// HostAddress returns the host and port on which containerPort (e.g. "6379/tcp") of resource can be
+// reached from the test process. Unlike resource.GetHostPort, it takes the Docker endpoint of pool
+// into account, so the address also works when DOCKER_HOST points at a remote machine or a VM.
+// If no host port was assigned for containerPort, it returns an error.
+func HostAddress(pool *dockertest.Pool, resource *dockertest.Resource, containerPort string) (host string, port string, err error) {
+	return internal.HostAddress(pool, resource, containerPort)
+}
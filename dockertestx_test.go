@@ -0,0 +1,721 @@
+package dockertestx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/vvatanabe/dockertestx"
+	"github.com/vvatanabe/dockertestx/internal"
+	"github.com/vvatanabe/dockertestx/redis"
+	"github.com/vvatanabe/dockertestx/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunContainer demonstrates running an arbitrary HTTP image and waiting for a 200 on a health path.
+func TestRunContainer(t *testing.T) {
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "nginx",
+		Tag:         "1.27-alpine",
+		ExposedPort: "80/tcp",
+		ReadyFunc: func(hostPort string) error {
+			client := http.Client{Timeout: 5 * time.Second}
+			resp, err := client.Get(fmt.Sprintf("http://%s/", hostPort))
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			}
+			return nil
+		},
+	})
+	defer cleanup()
+
+	if container.HostPort == "" {
+		t.Fatal("expected a host port to be assigned")
+	}
+	if container.Resource == nil || container.Resource.Container == nil {
+		t.Fatal("expected the underlying resource to be exposed")
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", container.HostPort))
+	if err != nil {
+		t.Fatalf("failed to request the container: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+// recordingTB captures log output and turns Fatal calls into a goroutine exit,
+// so that a failing Run can be observed from a test.
+type recordingTB struct {
+	testing.TB
+	mu     sync.Mutex
+	logs   []string
+	failed bool
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Logf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatal(args ...any) {
+	r.fail(fmt.Sprint(args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.fail(fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) fail(msg string) {
+	r.mu.Lock()
+	r.failed = true
+	r.logs = append(r.logs, msg)
+	r.mu.Unlock()
+	runtime.Goexit()
+}
+
+// TestWithLoggerDumpsLogsOnFailure verifies that the logs of a container that never becomes ready
+// are written into the test log.
+func TestWithLoggerDumpsLogsOnFailure(t *testing.T) {
+	rec := &recordingTB{TB: t}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, cleanup := dockertestx.RunContainer(rec, dockertestx.RunContainerConfig{
+			Repository:  "busybox",
+			Tag:         "1.36",
+			Cmd:         []string{"sh", "-c", "echo dockertestx-startup-failure; sleep 60"},
+			ExposedPort: "8080/tcp",
+			ReadyFunc: func(hostPort string) error {
+				// Give the container a moment to write its output, then give up for good
+				time.Sleep(2 * time.Second)
+				return backoff.Permanent(errors.New("never ready"))
+			},
+			RunOptions: []func(*dockertest.RunOptions){dockertestx.WithLogger()},
+		})
+		cleanup()
+	}()
+	<-done
+
+	if !rec.failed {
+		t.Fatal("expected RunContainer to fail")
+	}
+	output := strings.Join(rec.logs, "\n")
+	if !strings.Contains(output, "dockertestx-startup-failure") {
+		t.Errorf("expected the container logs in the test output, got:\n%s", output)
+	}
+}
+
+// TestRestartKeepsVolumeData writes to Redis with append-only persistence on a named volume,
+// restarts the container and reads the data back.
+func TestRestartKeepsVolumeData(t *testing.T) {
+	volume := fmt.Sprintf("dockertestx-restart-%d", time.Now().UnixNano())
+	var resource *dockertest.Resource
+	client, cleanup := redis.RunWithOptions(t, []func(*dockertest.RunOptions){
+		dockertestx.WithVolume(volume, "/data"),
+		dockertestx.WithResource(&resource),
+		func(opts *dockertest.RunOptions) {
+			opts.Cmd = []string{"redis-server", "--appendonly", "yes", "--appendfsync", "always"}
+		},
+	})
+	defer func() {
+		// The named volume outlives the container, so remove it once the container is gone.
+		cleanup()
+		pool, err := dockertest.NewPool("")
+		if err != nil {
+			t.Logf("failed to connect to docker: %s", err)
+			return
+		}
+		if err := pool.Client.RemoveVolumeWithOptions(docker.RemoveVolumeOptions{Name: volume}); err != nil {
+			t.Logf("failed to remove volume: %s", err)
+		}
+	}()
+
+	ctx := context.Background()
+	if err := client.Set(ctx, "greeting", "hello", 0).Err(); err != nil {
+		t.Fatalf("failed to set key: %v", err)
+	}
+
+	if err := dockertestx.Restart(t, resource); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+
+	// The host port may have changed, so connect again.
+	restarted := goredis.NewClient(&goredis.Options{Addr: resource.GetHostPort("6379/tcp")})
+	defer restarted.Close()
+
+	var got string
+	if err := backoff.Retry(func() error {
+		var err error
+		got, err = restarted.Get(ctx, "greeting").Result()
+		return err
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(500*time.Millisecond), 60)); err != nil {
+		t.Fatalf("failed to get key after restart: %v", err)
+	}
+	if got != "hello" {
+		t.Errorf("expected 'hello' after restart, but got '%s'", got)
+	}
+}
+
+func TestResourceLimitOptions(t *testing.T) {
+	hc := &docker.HostConfig{}
+	dockertestx.WithMemoryLimit(256 << 20)(hc)
+	dockertestx.WithCPUShares(512)(hc)
+
+	if hc.Memory != 256<<20 {
+		t.Errorf("expected Memory %d, but got %d", 256<<20, hc.Memory)
+	}
+	if hc.MemorySwap != 256<<20 {
+		t.Errorf("expected MemorySwap %d, but got %d", 256<<20, hc.MemorySwap)
+	}
+	if hc.CPUShares != 512 {
+		t.Errorf("expected CPUShares 512, but got %d", hc.CPUShares)
+	}
+}
+
+// TestWaitForHTTP demonstrates waiting for a container that starts serving after a delay.
+func TestWaitForHTTP(t *testing.T) {
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "busybox",
+		Tag:         "1.36",
+		Cmd:         []string{"sh", "-c", "sleep 3 && mkdir -p /www && echo ok > /www/index.html && httpd -f -p 8080 -h /www"},
+		ExposedPort: "8080/tcp",
+	})
+	defer cleanup()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	if err := dockertestx.WaitForHTTP(pool, fmt.Sprintf("http://%s/", container.HostPort), http.StatusOK, 30*time.Second); err != nil {
+		t.Fatalf("WaitForHTTP failed: %v", err)
+	}
+}
+
+// TestWaitForHTTPStatus verifies that WaitForHTTP waits for the wanted status and honors the timeout.
+func TestWaitForHTTPStatus(t *testing.T) {
+	ready := time.Now().Add(500 * time.Millisecond)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if time.Now().Before(ready) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	// Test case 1: The endpoint eventually returns the wanted status.
+	if err := dockertestx.WaitForHTTP(pool, srv.URL, http.StatusNoContent, 10*time.Second); err != nil {
+		t.Errorf("WaitForHTTP failed: %v", err)
+	}
+
+	// Test case 2: The endpoint never returns the wanted status.
+	start := time.Now()
+	err = dockertestx.WaitForHTTP(pool, srv.URL, http.StatusOK, time.Second)
+	if err == nil {
+		t.Fatal("expected an error for a status that never occurs")
+	}
+	if !strings.Contains(err.Error(), "got 204, want 200") {
+		t.Errorf("expected the error to name the status codes, but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected WaitForHTTP to give up after about 1s, but it took %s", elapsed)
+	}
+}
+
+// TestContainerLogs demonstrates asserting on what a container logged.
+func TestContainerLogs(t *testing.T) {
+	var resource *dockertest.Resource
+	_, cleanup := sql.RunPostgresWithOptions(t, []func(*dockertest.RunOptions){
+		dockertestx.WithResource(&resource),
+	})
+	defer cleanup()
+
+	logs, err := dockertestx.ContainerLogs(t, resource)
+	if err != nil {
+		t.Fatalf("ContainerLogs failed: %v", err)
+	}
+	if !strings.Contains(logs, "database system is ready to accept connections") {
+		t.Errorf("expected the logs to report readiness, but got:\n%s", logs)
+	}
+}
+
+// TestWithLabels verifies that the labels end up on the created container.
+func TestWithLabels(t *testing.T) {
+	labels := map[string]string{
+		"project":   "dockertestx",
+		"ci-run-id": fmt.Sprintf("%d", time.Now().UnixNano()),
+	}
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "nginx",
+		Tag:         "1.27-alpine",
+		ExposedPort: "80/tcp",
+		RunOptions: []func(*dockertest.RunOptions){
+			dockertestx.WithLabels(labels),
+		},
+	})
+	defer cleanup()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	inspected, err := pool.Client.InspectContainer(container.Resource.Container.ID)
+	if err != nil {
+		t.Fatalf("failed to inspect container: %v", err)
+	}
+	for k, want := range labels {
+		if got := inspected.Config.Labels[k]; got != want {
+			t.Errorf("expected label %s=%s, but got %q", k, want, got)
+		}
+	}
+}
+
+// TestWithExtraHostsAndDNS checks that the extra hosts and DNS servers end up in the container's HostConfig.
+func TestWithExtraHostsAndDNS(t *testing.T) {
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "nginx",
+		Tag:         "1.27-alpine",
+		ExposedPort: "80/tcp",
+		RunOptions: []func(*dockertest.RunOptions){
+			dockertestx.WithExtraHosts("host.docker.internal:host-gateway", "api.example.test:10.0.0.1"),
+			dockertestx.WithDNS("1.1.1.1"),
+		},
+	})
+	defer cleanup()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	inspected, err := pool.Client.InspectContainer(container.Resource.Container.ID)
+	if err != nil {
+		t.Fatalf("failed to inspect container: %v", err)
+	}
+	for _, want := range []string{"host.docker.internal:host-gateway", "api.example.test:10.0.0.1"} {
+		if !slices.Contains(inspected.HostConfig.ExtraHosts, want) {
+			t.Errorf("expected extra host %q, but got %v", want, inspected.HostConfig.ExtraHosts)
+		}
+	}
+	if !slices.Equal(inspected.HostConfig.DNS, []string{"1.1.1.1"}) {
+		t.Errorf("expected DNS [1.1.1.1], but got %v", inspected.HostConfig.DNS)
+	}
+}
+
+// TestEnableReaper registers a container with the reaper, simulates an abrupt exit of the test
+// process by dropping the reaper connection without running the cleanup, and waits until the
+// reaper has removed the container.
+func TestEnableReaper(t *testing.T) {
+	dockertestx.EnableReaper(t)
+
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "nginx",
+		Tag:         "1.27-alpine",
+		ExposedPort: "80/tcp",
+	})
+	// The reaper is expected to remove the container first; this only guards against a failure.
+	defer cleanup()
+
+	id := container.Resource.Container.ID
+	if container.Resource.Container.Config.Labels[dockertestx.SessionLabel] == "" {
+		t.Fatalf("expected the container to carry the %s label", dockertestx.SessionLabel)
+	}
+
+	dockertestx.DisconnectReaper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	deadline := time.Now().Add(time.Minute)
+	for {
+		_, err := pool.Client.InspectContainer(id)
+		var noSuchContainer *docker.NoSuchContainer
+		if errors.As(err, &noSuchContainer) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the reaper did not remove container %s within a minute (last error: %v)", id, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// TestWithPublishedPort pins Redis to a chosen host port and connects to it directly.
+func TestWithPublishedPort(t *testing.T) {
+	hostPort, err := internal.FreePort()
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+
+	_, cleanup := redis.RunWithOptions(t, []func(*dockertest.RunOptions){
+		dockertestx.WithPublishedPort("6379/tcp", hostPort),
+	})
+	defer cleanup()
+
+	client := goredis.NewClient(&goredis.Options{Addr: "localhost:" + hostPort})
+	defer client.Close()
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to ping redis on the published port: %v", err)
+	}
+}
+
+// TestWithNetworkAlias starts two Redis containers on a shared network and lets each one reach
+// the other by its alias.
+func TestWithNetworkAlias(t *testing.T) {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	network, err := pool.CreateNetwork(fmt.Sprintf("dockertestx-alias-%d", time.Now().UnixNano()))
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+	defer func() {
+		if err := pool.RemoveNetwork(network); err != nil {
+			t.Logf("failed to remove network: %v", err)
+		}
+	}()
+
+	run := func(name string) (*dockertest.Resource, func()) {
+		var resource *dockertest.Resource
+		_, cleanup := redis.RunWithOptions(t, []func(*dockertest.RunOptions){
+			func(opts *dockertest.RunOptions) {
+				opts.Networks = append(opts.Networks, network)
+			},
+			dockertestx.WithHostname(name),
+			dockertestx.WithNetworkAlias(name),
+			dockertestx.WithResource(&resource),
+		})
+		return resource, cleanup
+	}
+	cacheA, cleanupA := run("cache-a")
+	defer cleanupA()
+	cacheB, cleanupB := run("cache-b")
+	defer cleanupB()
+
+	exec := func(resource *dockertest.Resource, cmd ...string) string {
+		var stdout bytes.Buffer
+		code, err := resource.Exec(cmd, dockertest.ExecOptions{StdOut: &stdout})
+		if err != nil || code != 0 {
+			t.Fatalf("%v failed with exit code %d: %v", cmd, code, err)
+		}
+		return strings.TrimSpace(stdout.String())
+	}
+
+	if got := exec(cacheA, "hostname"); got != "cache-a" {
+		t.Errorf("expected hostname 'cache-a', but got %q", got)
+	}
+	if got := exec(cacheA, "redis-cli", "-h", "cache-b", "ping"); got != "PONG" {
+		t.Errorf("expected cache-a to reach cache-b, but got %q", got)
+	}
+	if got := exec(cacheB, "redis-cli", "-h", "cache-a", "ping"); got != "PONG" {
+		t.Errorf("expected cache-b to reach cache-a, but got %q", got)
+	}
+}
+
+func TestHostAddress(t *testing.T) {
+	resource := &dockertest.Resource{
+		Container: &docker.Container{
+			NetworkSettings: &docker.NetworkSettings{
+				Ports: map[docker.Port][]docker.PortBinding{
+					"6379/tcp": {{HostIP: "0.0.0.0", HostPort: "55001"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		dockerHost string
+		wantHost   string
+	}{
+		{name: "unix socket", dockerHost: "unix:///var/run/docker.sock", wantHost: "localhost"},
+		{name: "remote daemon", dockerHost: "tcp://192.168.99.100:2376", wantHost: "192.168.99.100"},
+		{name: "local TCP daemon", dockerHost: "tcp://localhost:2375", wantHost: "localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", tt.dockerHost)
+			t.Setenv("DOCKER_CERT_PATH", "")
+			t.Setenv("DOCKER_MACHINE_NAME", "")
+
+			pool, err := dockertest.NewPool("")
+			if err != nil {
+				t.Fatalf("failed to create pool: %v", err)
+			}
+
+			host, port, err := dockertestx.HostAddress(pool, resource, "6379/tcp")
+			if err != nil {
+				t.Fatalf("HostAddress failed: %v", err)
+			}
+			if host != tt.wantHost || port != "55001" {
+				t.Errorf("HostAddress = %q, %q; want %q, %q", host, port, tt.wantHost, "55001")
+			}
+		})
+	}
+
+	pool, err := dockertest.NewPool("unix:///var/run/docker.sock")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	if _, _, err := dockertestx.HostAddress(pool, resource, "6380/tcp"); err == nil {
+		t.Error("expected an error for a port that is not published")
+	}
+}
+
+func TestWithBackoff(t *testing.T) {
+	opts := &dockertest.RunOptions{}
+	dockertestx.WithBackoff(time.Second, 10*time.Second, 1.5)(opts)
+
+	got := internal.TakeSettings(opts).Backoff
+	want := internal.Backoff{Initial: time.Second, Max: 10 * time.Second, Factor: 1.5}
+	if got == nil || *got != want {
+		t.Errorf("expected Backoff %+v, but got %+v", want, got)
+	}
+}
+
+func TestWithEntrypointSetsField(t *testing.T) {
+	opts := &dockertest.RunOptions{}
+	dockertestx.WithEntrypoint("sh", "-c")(opts)
+
+	if want := []string{"sh", "-c"}; !slices.Equal(opts.Entrypoint, want) {
+		t.Errorf("expected Entrypoint %v, but got %v", want, opts.Entrypoint)
+	}
+}
+
+// TestWithEntrypoint replaces the nginx entrypoint with a shell that writes a custom page before
+// starting the server.
+func TestWithEntrypoint(t *testing.T) {
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "nginx",
+		Tag:         "1.27-alpine",
+		ExposedPort: "80/tcp",
+		// The image's default command is passed to sh as positional parameters and ignored.
+		RunOptions: []func(*dockertest.RunOptions){
+			dockertestx.WithEntrypoint("sh", "-c", "echo overridden > /usr/share/nginx/html/index.html && exec nginx -g 'daemon off;'"),
+		},
+	})
+	defer cleanup()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	url := fmt.Sprintf("http://%s/", container.HostPort)
+	if err := dockertestx.WaitForHTTP(pool, url, http.StatusOK, 30*time.Second); err != nil {
+		t.Fatalf("WaitForHTTP failed: %v", err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("failed to request the container: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read the response: %v", err)
+	}
+	if got := strings.TrimSpace(string(body)); got != "overridden" {
+		t.Errorf("expected the page written by the entrypoint, but got %q", got)
+	}
+}
+
+// TestWithUserAndWorkingDir runs a container as an unprivileged user from a custom directory.
+func TestWithUserAndWorkingDir(t *testing.T) {
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "nginx",
+		Tag:         "1.27-alpine",
+		ExposedPort: "80/tcp",
+		// An unprivileged user cannot bind port 80, so keep the container alive without nginx.
+		Cmd: []string{"sleep", "300"},
+		RunOptions: []func(*dockertest.RunOptions){
+			dockertestx.WithUser("1000:1000"),
+			dockertestx.WithWorkingDir("/tmp"),
+		},
+	})
+	defer cleanup()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	inspected, err := pool.Client.InspectContainer(container.Resource.Container.ID)
+	if err != nil {
+		t.Fatalf("failed to inspect container: %v", err)
+	}
+	if inspected.Config.User != "1000:1000" {
+		t.Errorf("expected user %q, but got %q", "1000:1000", inspected.Config.User)
+	}
+	if inspected.Config.WorkingDir != "/tmp" {
+		t.Errorf("expected working directory %q, but got %q", "/tmp", inspected.Config.WorkingDir)
+	}
+
+	var stdout bytes.Buffer
+	code, err := container.Resource.Exec([]string{"sh", "-c", "id -u && pwd"}, dockertest.ExecOptions{StdOut: &stdout})
+	if err != nil || code != 0 {
+		t.Fatalf("failed to run id in the container: code %d, %v", code, err)
+	}
+	if got := strings.Fields(stdout.String()); !slices.Equal(got, []string{"1000", "/tmp"}) {
+		t.Errorf("expected uid 1000 in /tmp, but got %v", got)
+	}
+}
+
+// TestWithBindMount mounts a fixture file read-only and reads it from inside the container.
+func TestWithBindMount(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatalf("failed to change permissions: %v", err)
+	}
+	hostPath := filepath.Join(dir, "fixture.txt")
+	if err := os.WriteFile(hostPath, []byte("hello from the host\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	container, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "nginx",
+		Tag:         "1.27-alpine",
+		ExposedPort: "80/tcp",
+		RunOptions: []func(*dockertest.RunOptions){
+			dockertestx.WithBindMount(hostPath, "/fixtures/fixture.txt", true),
+		},
+	})
+	defer cleanup()
+
+	var stdout, stderr bytes.Buffer
+	code, err := container.Resource.Exec([]string{"cat", "/fixtures/fixture.txt"}, dockertest.ExecOptions{
+		StdOut: &stdout,
+		StdErr: &stderr,
+	})
+	if err != nil || code != 0 {
+		t.Fatalf("failed to read the mounted file (exit code %d): %v %s", code, err, stderr.String())
+	}
+	if got := stdout.String(); got != "hello from the host\n" {
+		t.Errorf("expected the fixture content, but got %q", got)
+	}
+
+	// The mount is read-only
+	code, err = container.Resource.Exec([]string{"sh", "-c", "echo changed > /fixtures/fixture.txt"}, dockertest.ExecOptions{})
+	if err != nil {
+		t.Fatalf("failed to exec in the container: %v", err)
+	}
+	if code == 0 {
+		t.Error("expected writing to a read-only mount to fail")
+	}
+}
+
+func TestWithBindMountOptions(t *testing.T) {
+	opts := &dockertest.RunOptions{}
+	dockertestx.WithBindMount("/srv/config.yaml", "/etc/app/config.yaml", true)(opts)
+	dockertestx.WithBindMount("/srv/data", "/data", false)(opts)
+
+	want := []string{"/srv/config.yaml:/etc/app/config.yaml:ro", "/srv/data:/data"}
+	if !slices.Equal(opts.Mounts, want) {
+		t.Errorf("expected Mounts %v, but got %v", want, opts.Mounts)
+	}
+}
+
+func TestWithEnvMap(t *testing.T) {
+	opts := &dockertest.RunOptions{
+		Env: []string{"POSTGRES_PASSWORD=secret", "POSTGRES_DB=test", "POSTGRES_DB=dup"},
+	}
+	dockertestx.WithEnvMap(map[string]string{
+		"POSTGRES_DB": "app",
+		"TZ":          "UTC",
+		"LANG":        "C.UTF-8",
+	})(opts)
+
+	want := []string{"POSTGRES_PASSWORD=secret", "POSTGRES_DB=app", "LANG=C.UTF-8", "TZ=UTC"}
+	if !slices.Equal(opts.Env, want) {
+		t.Errorf("expected Env %v, but got %v", want, opts.Env)
+	}
+}
+
+// TestWithEnvMapOverridesDefault replaces the default database of the postgres package and checks
+// that the returned connection uses it.
+func TestWithEnvMapOverridesDefault(t *testing.T) {
+	db, cleanup := sql.RunPostgresWithOptions(t, []func(*dockertest.RunOptions){
+		dockertestx.WithEnvMap(map[string]string{"POSTGRES_DB": "app"}),
+	})
+	defer cleanup()
+
+	var name string
+	if err := db.QueryRow("SELECT current_database()").Scan(&name); err != nil {
+		t.Fatalf("failed to query the current database: %v", err)
+	}
+	if name != "app" {
+		t.Errorf("expected database 'app', but got %q", name)
+	}
+}
+
+// TestWithRetryHook starts a container that only serves after a delay and records the failed
+// readiness attempts through the hook.
+func TestWithRetryHook(t *testing.T) {
+	var attempts []int
+	var lastErr error
+	_, cleanup := dockertestx.RunContainer(t, dockertestx.RunContainerConfig{
+		Repository:  "busybox",
+		Tag:         "1.36",
+		Cmd:         []string{"sh", "-c", "sleep 3 && mkdir -p /www && echo ok > /www/index.html && httpd -f -p 8080 -h /www"},
+		ExposedPort: "8080/tcp",
+		ReadyFunc: func(hostPort string) error {
+			resp, err := http.Get(fmt.Sprintf("http://%s/", hostPort))
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			}
+			return nil
+		},
+		RunOptions: []func(*dockertest.RunOptions){
+			dockertestx.WithRetryHook(func(attempt int, err error) {
+				attempts = append(attempts, attempt)
+				lastErr = err
+			}),
+		},
+	})
+	defer cleanup()
+
+	if len(attempts) == 0 {
+		t.Fatal("expected the hook to be called for the attempts before the server started")
+	}
+	for i, attempt := range attempts {
+		if attempt != i+1 {
+			t.Fatalf("expected increasing attempt numbers, but got %v", attempts)
+		}
+	}
+	if lastErr == nil {
+		t.Error("expected the hook to receive the attempt's error")
+	}
+}
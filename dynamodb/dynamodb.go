@@ -1,15 +1,28 @@
 package dynamodb
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamtypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"io"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -18,8 +31,23 @@ const (
 	defaultDynamoDBImage = "amazon/dynamodb-local"
 	defaultDynamoDBTag   = "latest"
 	defaultRegion        = "us-east-1"
+	defaultAccessKey     = "dummy"
+	defaultSecretKey     = "dummy"
 )
 
+// Endpoint describes how to reach a DynamoDB Local container started by RunWithEndpoint,
+// so that callers can construct their own clients (e.g. in a subprocess or with another SDK).
+type Endpoint struct {
+	// URL is the endpoint URL of DynamoDB Local, e.g. "http://localhost:55001".
+	URL string
+	// Region is the region the client is configured with.
+	Region string
+	// AccessKeyID is the dummy access key accepted by DynamoDB Local.
+	AccessKeyID string
+	// SecretAccessKey is the dummy secret key accepted by DynamoDB Local.
+	SecretAccessKey string
+}
+
 // Run starts a DynamoDB Local Docker container using the default settings and returns
 // a connected *dynamodb.Client along with a cleanup function. It uses the DynamoDB Local image
 // ("amazon/dynamodb-local") with tag "latest". For more customization, use RunWithOptions.
@@ -37,6 +65,15 @@ func Run(t testing.TB) (*dynamodb.Client, func()) {
 func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*dynamodb.Client, func()) {
 	t.Helper()
 
+	client, _, cleanup := RunWithEndpoint(t, runOpts, hostOpts...)
+	return client, cleanup
+}
+
+// RunWithEndpoint behaves like RunWithOptions but additionally returns the Endpoint of the
+// started container, including the dummy credentials the returned client uses.
+func RunWithEndpoint(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*dynamodb.Client, Endpoint, func()) {
+	t.Helper()
+
 	// Set default options for DynamoDB Local
 	defaultRunOpts := &dockertest.RunOptions{
 		Repository: defaultDynamoDBImage,
@@ -48,6 +85,8 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		opt(defaultRunOpts)
 	}
 
+	settings := internal.TakeSettings(defaultRunOpts)
+
 	// Create a new Docker pool
 	pool, err := dockertest.NewPool("")
 	if err != nil {
@@ -60,36 +99,48 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		t.Fatalf("failed to start dynamodb container: %s", err)
 	}
 
-	// Get the mapped port
-	actualPort := resource.GetPort("8000/tcp")
-	if actualPort == "" {
+	// Get the mapped address
+	host, port, err := internal.HostAddress(pool, resource, "8000/tcp")
+	if err != nil {
 		_ = pool.Purge(resource)
-		t.Fatalf("no host port was assigned for the dynamodb container")
+		t.Fatalf("failed to get the address of the dynamodb container: %s", err)
 	}
+	actualPort := net.JoinHostPort(host, port)
 	t.Logf("DynamoDB container is running on host port '%s'", actualPort)
 
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure dynamodb container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
 	// Configure AWS SDK v2
-	endpoint := fmt.Sprintf("http://localhost:%s", actualPort)
+	endpoint := Endpoint{
+		URL:             "http://" + actualPort,
+		Region:          defaultRegion,
+		AccessKeyID:     defaultAccessKey,
+		SecretAccessKey: defaultSecretKey,
+	}
 
 	// Create a DynamoDB client with retry mechanism
 	var client *dynamodb.Client
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err = pool.Retry(func() error {
+	if err = settings.Retry(pool, func() error {
 		// Configure AWS SDK credentials and endpoint
 		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
 			return aws.Endpoint{
-				URL:           endpoint,
-				SigningRegion: defaultRegion,
+				URL:           endpoint.URL,
+				SigningRegion: endpoint.Region,
 			}, nil
 		})
 
 		// Create AWS config
 		cfg, err := config.LoadDefaultConfig(ctx,
-			config.WithRegion(defaultRegion),
+			config.WithRegion(endpoint.Region),
 			config.WithEndpointResolverWithOptions(customResolver),
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("dummy", "dummy", "dummy")),
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(endpoint.AccessKeyID, endpoint.SecretAccessKey, "dummy")),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to configure AWS SDK: %w", err)
@@ -104,23 +155,101 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		})
 		return err
 	}); err != nil {
+		logs.Dump()
 		_ = pool.Purge(resource)
 		t.Fatalf("failed to connect to dynamodb: %s", err)
 	}
 
 	// Create cleanup function
 	cleanup := func() {
+		logs.Stop()
 		if err := pool.Purge(resource); err != nil {
 			t.Logf("failed to remove dynamodb container: %s", err)
 		}
 	}
 
-	return client, cleanup
+	return client, endpoint, cleanup
+}
+
+// WithInMemory returns a RunOption that starts DynamoDB Local with "-jar DynamoDBLocal.jar -inMemory",
+// which is also what the image runs by default. Data lives only in memory, which is fast, and each
+// combination of access key and region gets its own separate database.
+func WithInMemory() func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Cmd = []string{"-jar", "DynamoDBLocal.jar", "-inMemory"}
+	}
+}
+
+// WithSharedDB returns a RunOption that starts DynamoDB Local with "-jar DynamoDBLocal.jar -sharedDb".
+// All clients then see a single database regardless of their access key and region, at the cost
+// of the database being file-backed inside the container (slower than -inMemory). The file is
+// discarded together with the container on cleanup.
+func WithSharedDB() func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Cmd = []string{"-jar", "DynamoDBLocal.jar", "-sharedDb"}
+	}
+}
+
+// NewStreamsClient returns a *dynamodbstreams.Client pointed at the same DynamoDB Local
+// endpoint, for use with tables created with WithStream.
+func NewStreamsClient(endpoint Endpoint) *dynamodbstreams.Client {
+	return dynamodbstreams.New(dynamodbstreams.Options{
+		Region:       endpoint.Region,
+		BaseEndpoint: aws.String(endpoint.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider(endpoint.AccessKeyID, endpoint.SecretAccessKey, "dummy"),
+	})
+}
+
+// TableOption customizes the table created by CreateDynamoDBTable.
+type TableOption func(*tableOptions)
+
+// tableOptions holds the settings that TableOption functions can override.
+type tableOptions struct {
+	input         *dynamodb.CreateTableInput
+	activeTimeout time.Duration
+}
+
+// defaultTableActiveTimeout is how long CreateDynamoDBTable waits for a new table to become ACTIVE.
+const defaultTableActiveTimeout = 30 * time.Second
+
+// WithActiveTimeout sets how long CreateDynamoDBTable waits for the created table to become
+// ACTIVE before returning an error. The default is 30 seconds.
+func WithActiveTimeout(timeout time.Duration) TableOption {
+	return func(o *tableOptions) {
+		o.activeTimeout = timeout
+	}
+}
+
+// WithBillingMode sets the billing mode of the created table. By default tables are created with
+// provisioned throughput of 5 read and 5 write capacity units. With types.BillingModePayPerRequest
+// the table uses on-demand billing and no ProvisionedThroughput is sent, since DynamoDB rejects
+// requests that specify both.
+func WithBillingMode(mode types.BillingMode) TableOption {
+	return func(o *tableOptions) {
+		o.input.BillingMode = mode
+		if mode == types.BillingModePayPerRequest {
+			o.input.ProvisionedThroughput = nil
+		}
+	}
+}
+
+// WithStream enables DynamoDB Streams on the created table with the given view type,
+// e.g. types.StreamViewTypeNewAndOldImages. Use the table's LatestStreamArn with
+// GetDynamoDBStreamRecords to read the resulting records.
+func WithStream(viewType types.StreamViewType) TableOption {
+	return func(o *tableOptions) {
+		o.input.StreamSpecification = &types.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: viewType,
+		}
+	}
 }
 
-// CreateDynamoDBTable creates a DynamoDB table with the given name, key schema, and attribute definitions.
+// CreateDynamoDBTable creates a DynamoDB table with the given name, key schema, and attribute definitions,
+// and waits until the table is ACTIVE so that it can be written to immediately.
 // If the table already exists, it will not return an error.
-func CreateDynamoDBTable(t testing.TB, client *dynamodb.Client, tableName string, keySchema []types.KeySchemaElement, attributeDefs []types.AttributeDefinition) error {
+// Optional TableOption functions can be provided via opts to customize the table (e.g. WithBillingMode).
+func CreateDynamoDBTable(t testing.TB, client *dynamodb.Client, tableName string, keySchema []types.KeySchemaElement, attributeDefs []types.AttributeDefinition, opts ...TableOption) error {
 	t.Helper()
 
 	ctx := context.Background()
@@ -138,47 +267,752 @@ func CreateDynamoDBTable(t testing.TB, client *dynamodb.Client, tableName string
 		}
 	}
 
-	// Create table
-	_, err = client.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName:            aws.String(tableName),
-		KeySchema:            keySchema,
-		AttributeDefinitions: attributeDefs,
-		ProvisionedThroughput: &types.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(5),
-			WriteCapacityUnits: aws.Int64(5),
+	// Set default table options
+	options := &tableOptions{
+		input: &dynamodb.CreateTableInput{
+			TableName:            aws.String(tableName),
+			KeySchema:            keySchema,
+			AttributeDefinitions: attributeDefs,
+			ProvisionedThroughput: &types.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(5),
+				WriteCapacityUnits: aws.Int64(5),
+			},
 		},
-	})
+		activeTimeout: defaultTableActiveTimeout,
+	}
+
+	// Apply any provided TableOption functions to override defaults
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// Create table
+	_, err = client.CreateTable(ctx, options.input)
 
 	if err != nil {
 		return fmt.Errorf("failed to create table %s: %w", tableName, err)
 	}
 
+	// Wait until the table is ACTIVE to avoid racing follow-up writes against CREATING
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	}, options.activeTimeout); err != nil {
+		return fmt.Errorf("failed waiting for table %s to become active: %w", tableName, err)
+	}
+
 	t.Logf("Created table %s", tableName)
 	return nil
 }
 
+// CreateTableFromStruct creates a DynamoDB table whose key schema is derived from the struct v
+// (or a pointer to it). Key attributes are marked with a `dynamodbkey:"hash"` or
+// `dynamodbkey:"range"` tag; their attribute names are taken from the `dynamodbav` tag when
+// present, or the field name otherwise. Key fields must be strings (S), numbers (N), or []byte (B).
+// The table is created with CreateDynamoDBTable, so the same TableOption functions apply.
+//
+//	type Order struct {
+//		CustomerID string `dynamodbav:"customer_id" dynamodbkey:"hash"`
+//		OrderID    int64  `dynamodbav:"order_id" dynamodbkey:"range"`
+//		Total      int    `dynamodbav:"total"`
+//	}
+func CreateTableFromStruct(t testing.TB, client *dynamodb.Client, tableName string, v any, opts ...TableOption) error {
+	t.Helper()
+
+	keySchema, attributeDefs, err := keySchemaFromStruct(v)
+	if err != nil {
+		return fmt.Errorf("failed to derive key schema for table %s: %w", tableName, err)
+	}
+
+	return CreateDynamoDBTable(t, client, tableName, keySchema, attributeDefs, opts...)
+}
+
+// keySchemaFromStruct derives the key schema and key attribute definitions from the
+// dynamodbkey and dynamodbav tags of a struct.
+func keySchemaFromStruct(v any) ([]types.KeySchemaElement, []types.AttributeDefinition, error) {
+	typ := reflect.TypeOf(v)
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("expected a struct, got %T", v)
+	}
+
+	var hash, rng *types.KeySchemaElement
+	var attributeDefs []types.AttributeDefinition
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		keyTag, ok := field.Tag.Lookup("dynamodbkey")
+		if !ok {
+			continue
+		}
+
+		name := field.Name
+		if av, _, _ := strings.Cut(field.Tag.Get("dynamodbav"), ","); av != "" && av != "-" {
+			name = av
+		}
+
+		attrType, err := scalarAttributeType(field.Type)
+		if err != nil {
+			return nil, nil, fmt.Errorf("key field %s: %w", field.Name, err)
+		}
+
+		element := &types.KeySchemaElement{AttributeName: aws.String(name)}
+		switch keyTag {
+		case "hash":
+			if hash != nil {
+				return nil, nil, fmt.Errorf("multiple hash keys: %s and %s", aws.ToString(hash.AttributeName), name)
+			}
+			element.KeyType = types.KeyTypeHash
+			hash = element
+		case "range":
+			if rng != nil {
+				return nil, nil, fmt.Errorf("multiple range keys: %s and %s", aws.ToString(rng.AttributeName), name)
+			}
+			element.KeyType = types.KeyTypeRange
+			rng = element
+		default:
+			return nil, nil, fmt.Errorf("key field %s: invalid dynamodbkey tag %q, expected \"hash\" or \"range\"", field.Name, keyTag)
+		}
+
+		attributeDefs = append(attributeDefs, types.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: attrType,
+		})
+	}
+
+	if hash == nil {
+		return nil, nil, fmt.Errorf("no field of %s is tagged with dynamodbkey:\"hash\"", typ)
+	}
+
+	keySchema := []types.KeySchemaElement{*hash}
+	if rng != nil {
+		keySchema = append(keySchema, *rng)
+	}
+	return keySchema, attributeDefs, nil
+}
+
+// scalarAttributeType maps a Go type to the DynamoDB scalar attribute type used for keys.
+func scalarAttributeType(typ reflect.Type) (types.ScalarAttributeType, error) {
+	switch typ.Kind() {
+	case reflect.String:
+		return types.ScalarAttributeTypeS, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return types.ScalarAttributeTypeN, nil
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return types.ScalarAttributeTypeB, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported key type %s, expected a string, number, or []byte", typ)
+}
+
+const (
+	// maxBatchWriteItems is the maximum number of requests DynamoDB accepts in one BatchWriteItem call.
+	maxBatchWriteItems = 25
+	// batchWriteTimeout bounds how long unprocessed items are retried before giving up.
+	batchWriteTimeout = 30 * time.Second
+)
+
 // PrepDynamoDBItems inserts the provided items into the specified DynamoDB table.
 // It accepts tableName and a list of items as map[string]types.AttributeValue.
+// Items are written with BatchWriteItem in chunks of 25, and any UnprocessedItems
+// reported by DynamoDB are retried until they are written or 30 seconds have elapsed.
 func PrepDynamoDBItems(t testing.TB, client *dynamodb.Client, tableName string, items []map[string]types.AttributeValue) error {
 	t.Helper()
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), batchWriteTimeout)
+	defer cancel()
+
+	requests := make([]types.WriteRequest, 0, len(items))
+	for _, item := range items {
+		requests = append(requests, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: item},
+		})
+	}
+
+	if err := batchWrite(ctx, client, tableName, requests); err != nil {
+		return fmt.Errorf("failed to insert items into table %s: %w", tableName, err)
+	}
+
+	t.Logf("Inserted %d items into table %s", len(items), tableName)
+	return nil
+}
+
+// PrepDynamoDBTransaction writes the provided items atomically with TransactWriteItems: either all
+// Put, Update, Delete and ConditionCheck operations succeed or none of them is applied. A single
+// transaction accepts at most 100 items. If the transaction is canceled, e.g. because a condition
+// expression failed, the returned error lists the reason reported for each canceled item and wraps
+// the *types.TransactionCanceledException.
+func PrepDynamoDBTransaction(t testing.TB, client *dynamodb.Client, items []types.TransactWriteItem) error {
+	t.Helper()
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			return fmt.Errorf("transaction of %d items was canceled: %s: %w", len(items), cancellationReasons(canceled), err)
+		}
+		return fmt.Errorf("failed to write transaction of %d items: %w", len(items), err)
+	}
+
+	t.Logf("Wrote %d items in a transaction", len(items))
+	return nil
+}
+
+// cancellationReasons describes the items of a canceled transaction that caused the cancellation,
+// e.g. "item 1: ConditionalCheckFailed (The conditional request failed)". DynamoDB reports the code
+// "None" for the items that did not.
+func cancellationReasons(e *types.TransactionCanceledException) string {
+	var reasons []string
+	for i, r := range e.CancellationReasons {
+		code := aws.ToString(r.Code)
+		if code == "" || code == "None" {
+			continue
+		}
+		reason := fmt.Sprintf("item %d: %s", i, code)
+		if msg := aws.ToString(r.Message); msg != "" {
+			reason += fmt.Sprintf(" (%s)", msg)
+		}
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) == 0 {
+		return "no reason reported"
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// JSONOption configures how PrepDynamoDBFromJSON decodes items.
+type JSONOption func(*jsonOptions)
+
+// jsonOptions holds the settings applied by JSONOption functions.
+type jsonOptions struct {
+	plain bool
+}
+
+// WithPlainJSON returns a JSONOption that decodes items as plain JSON objects, e.g. {"ID": "1", "Age": 30},
+// instead of DynamoDB JSON. Strings become S, numbers N, booleans BOOL, null NULL, arrays L and objects M.
+func WithPlainJSON() JSONOption {
+	return func(o *jsonOptions) {
+		o.plain = true
+	}
+}
+
+// PrepDynamoDBFromJSON decodes a JSON array of items from r and inserts them into the specified
+// DynamoDB table with PrepDynamoDBItems. By default the items are in DynamoDB JSON, the format used
+// by the AWS CLI, e.g. [{"ID": {"S": "1"}, "Age": {"N": "30"}}]; pass WithPlainJSON for plain objects.
+// If the input cannot be decoded or the items cannot be written, it returns an error.
+func PrepDynamoDBFromJSON(t testing.TB, client *dynamodb.Client, tableName string, r io.Reader, opts ...JSONOption) error {
+	t.Helper()
+
+	o := jsonOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	items, err := decodeJSONItems(r, o.plain)
+	if err != nil {
+		return fmt.Errorf("failed to decode items for table %s: %w", tableName, err)
+	}
+	return PrepDynamoDBItems(t, client, tableName, items)
+}
+
+// decodeJSONItems decodes a JSON array of items in DynamoDB JSON, or in plain JSON if plain is set.
+func decodeJSONItems(r io.Reader, plain bool) ([]map[string]types.AttributeValue, error) {
+	dec := json.NewDecoder(r)
+	if plain {
+		dec.UseNumber()
+		var raw []map[string]any
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		items := make([]map[string]types.AttributeValue, 0, len(raw))
+		for i, obj := range raw {
+			item := make(map[string]types.AttributeValue, len(obj))
+			for name, v := range obj {
+				av, err := plainAttributeValue(v)
+				if err != nil {
+					return nil, fmt.Errorf("item %d: attribute %q: %w", i, name, err)
+				}
+				item[name] = av
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	var raw []map[string]json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	items := make([]map[string]types.AttributeValue, 0, len(raw))
+	for i, obj := range raw {
+		item := make(map[string]types.AttributeValue, len(obj))
+		for name, v := range obj {
+			av, err := decodeAttributeValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: attribute %q: %w", i, name, err)
+			}
+			item[name] = av
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// decodeAttributeValue decodes a single attribute value in DynamoDB JSON, e.g. {"N": "30"}.
+func decodeAttributeValue(data json.RawMessage) (types.AttributeValue, error) {
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, err
+	}
+	if len(typed) != 1 {
+		return nil, fmt.Errorf("expected exactly one type descriptor, got %d", len(typed))
+	}
+
+	// typed has exactly one entry: the type descriptor and its value.
+	var typ string
+	var v json.RawMessage
+	for typ, v = range typed {
+	}
+
+	switch typ {
+	case "S":
+		av := &types.AttributeValueMemberS{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "N":
+		av := &types.AttributeValueMemberN{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "B":
+		av := &types.AttributeValueMemberB{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "BOOL":
+		av := &types.AttributeValueMemberBOOL{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "NULL":
+		av := &types.AttributeValueMemberNULL{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "SS":
+		av := &types.AttributeValueMemberSS{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "NS":
+		av := &types.AttributeValueMemberNS{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "BS":
+		av := &types.AttributeValueMemberBS{}
+		return av, json.Unmarshal(v, &av.Value)
+	case "L":
+		var raw []json.RawMessage
+		if err := json.Unmarshal(v, &raw); err != nil {
+			return nil, err
+		}
+		av := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, 0, len(raw))}
+		for _, e := range raw {
+			ev, err := decodeAttributeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			av.Value = append(av.Value, ev)
+		}
+		return av, nil
+	case "M":
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(v, &raw); err != nil {
+			return nil, err
+		}
+		av := &types.AttributeValueMemberM{Value: make(map[string]types.AttributeValue, len(raw))}
+		for name, e := range raw {
+			ev, err := decodeAttributeValue(e)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", name, err)
+			}
+			av.Value[name] = ev
+		}
+		return av, nil
+	default:
+		return nil, fmt.Errorf("unknown type descriptor %q", typ)
+	}
+}
+
+// plainAttributeValue converts a value decoded from plain JSON with UseNumber into an attribute value.
+func plainAttributeValue(v any) (types.AttributeValue, error) {
+	switch v := v.(type) {
+	case nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case string:
+		return &types.AttributeValueMemberS{Value: v}, nil
+	case json.Number:
+		return &types.AttributeValueMemberN{Value: v.String()}, nil
+	case bool:
+		return &types.AttributeValueMemberBOOL{Value: v}, nil
+	case []any:
+		av := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, 0, len(v))}
+		for _, e := range v {
+			ev, err := plainAttributeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			av.Value = append(av.Value, ev)
+		}
+		return av, nil
+	case map[string]any:
+		av := &types.AttributeValueMemberM{Value: make(map[string]types.AttributeValue, len(v))}
+		for name, e := range v {
+			ev, err := plainAttributeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			av.Value[name] = ev
+		}
+		return av, nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value of type %T", v)
+	}
+}
+
+// batchWriter is the subset of *dynamodb.Client used by batchWrite.
+type batchWriter interface {
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// batchWrite sends requests to tableName in chunks of maxBatchWriteItems, retrying
+// unprocessed requests with a growing delay until none remain or ctx is done.
+func batchWrite(ctx context.Context, client batchWriter, tableName string, requests []types.WriteRequest) error {
+	for start := 0; start < len(requests); start += maxBatchWriteItems {
+		end := min(start+maxBatchWriteItems, len(requests))
+
+		pending := map[string][]types.WriteRequest{tableName: requests[start:end]}
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt > 0 {
+				delay := min(time.Duration(attempt)*50*time.Millisecond, time.Second)
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("requests %d-%d: %d unprocessed requests remain: %w", start, end-1, len(pending[tableName]), ctx.Err())
+				case <-time.After(delay):
+				}
+			}
+
+			out, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				return fmt.Errorf("requests %d-%d: %w", start, end-1, err)
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// EnableDynamoDBTTL enables Time to Live on the specified table using attributeName as the
+// expiration attribute, and waits until DescribeTimeToLive reports the status ENABLED.
+// Note that DynamoDB Local stores and reports the TTL configuration but does not actively
+// delete expired items, so tests should assert on the configuration rather than on deletion.
+func EnableDynamoDBTTL(t testing.TB, client *dynamodb.Client, tableName, attributeName string) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(attributeName),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable TTL on table %s: %w", tableName, err)
+	}
 
-	for i, item := range items {
-		_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+	for {
+		resp, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{
 			TableName: aws.String(tableName),
-			Item:      item,
 		})
+		if err != nil {
+			return fmt.Errorf("failed to describe TTL of table %s: %w", tableName, err)
+		}
+		if resp.TimeToLiveDescription != nil && resp.TimeToLiveDescription.TimeToLiveStatus == types.TimeToLiveStatusEnabled {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for TTL to be enabled on table %s: %w", tableName, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	t.Logf("Enabled TTL on table %s using attribute %s", tableName, attributeName)
+	return nil
+}
+
+// ClearDynamoDBTable deletes every item from the specified table without dropping it, which
+// avoids waiting for the table to become ACTIVE again between subtests. The key schema is
+// discovered with DescribeTable, so only the key attributes are scanned before the items are
+// removed with batched DeleteRequests.
+func ClearDynamoDBTable(t testing.TB, client *dynamodb.Client, tableName string) error {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), batchWriteTimeout)
+	defer cancel()
+
+	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe table %s: %w", tableName, err)
+	}
 
+	// Project only the key attributes; placeholders avoid clashes with reserved words.
+	names := make(map[string]string, len(desc.Table.KeySchema))
+	projection := make([]string, 0, len(desc.Table.KeySchema))
+	for i, key := range desc.Table.KeySchema {
+		placeholder := fmt.Sprintf("#k%d", i)
+		names[placeholder] = aws.ToString(key.AttributeName)
+		projection = append(projection, placeholder)
+	}
+
+	var requests []types.WriteRequest
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:                aws.String(tableName),
+		ProjectionExpression:     aws.String(strings.Join(projection, ", ")),
+		ExpressionAttributeNames: names,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to insert item %d into table %s: %w", i, tableName, err)
+			return fmt.Errorf("failed to scan table %s: %w", tableName, err)
+		}
+		for _, key := range page.Items {
+			requests = append(requests, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: key},
+			})
 		}
 	}
 
-	t.Logf("Inserted %d items into table %s", len(items), tableName)
+	if err := batchWrite(ctx, client, tableName, requests); err != nil {
+		return fmt.Errorf("failed to delete items from table %s: %w", tableName, err)
+	}
+
+	t.Logf("Deleted %d items from table %s", len(requests), tableName)
 	return nil
 }
 
+// QueryDynamoDB runs a Query against the specified table with keyCond as the KeyConditionExpression
+// and exprValues as its ExpressionAttributeValues, following pagination until all matching items
+// have been collected.
+func QueryDynamoDB(t testing.TB, client *dynamodb.Client, tableName string, keyCond string, exprValues map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var items []map[string]types.AttributeValue
+	paginator := dynamodb.NewQueryPaginator(client, &dynamodb.QueryInput{
+		TableName:                 aws.String(tableName),
+		KeyConditionExpression:    aws.String(keyCond),
+		ExpressionAttributeValues: exprValues,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query table %s: %w", tableName, err)
+		}
+		items = append(items, page.Items...)
+	}
+
+	return items, nil
+}
+
+// ScanAllDynamoDB scans the specified table and returns every item, following pagination.
+// It is intended for the small tables typical of tests.
+func ScanAllDynamoDB(t testing.TB, client *dynamodb.Client, tableName string) ([]map[string]types.AttributeValue, error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var items []map[string]types.AttributeValue
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan table %s: %w", tableName, err)
+		}
+		items = append(items, page.Items...)
+	}
+
+	return items, nil
+}
+
+// CountDynamoDBItems returns the number of items in the specified table. It uses the ItemCount
+// reported by DescribeTable and falls back to a paginated Scan with Select=COUNT when the count is
+// not available or zero.
+//
+// ItemCount is eventually consistent: DynamoDB refreshes it only periodically, and DynamoDB Local
+// may also lag behind recent writes. A table that was just seeded therefore usually reports zero
+// and is counted with a Scan, but a count taken right after deleting items can be stale. Use
+// ScanAllDynamoDB when the assertion must reflect the latest writes.
+func CountDynamoDBItems(t testing.TB, client *dynamodb.Client, tableName string) (int64, error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+	}
+	if n := aws.ToInt64(desc.Table.ItemCount); n > 0 {
+		return n, nil
+	}
+
+	var count int64
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+		Select:    types.SelectCount,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count items in table %s: %w", tableName, err)
+		}
+		count += int64(page.Count)
+	}
+
+	return count, nil
+}
+
+// ExportDynamoDBTable scans every item of the specified table and returns them as plain Go values,
+// sorted by the table's partition key and then its sort key, so that the result can be compared
+// with a golden file, e.g. after encoding it with json.MarshalIndent. Items are unmarshaled with
+// attributevalue.UnmarshalListOfMaps: numbers become float64, binary values []byte and sets slices.
+func ExportDynamoDBTable(t testing.TB, client *dynamodb.Client, tableName string) ([]map[string]any, error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+	}
+
+	// The partition key comes first in the key schema, followed by the optional sort key.
+	keys := make([]string, 0, len(desc.Table.KeySchema))
+	for _, kt := range []types.KeyType{types.KeyTypeHash, types.KeyTypeRange} {
+		for _, key := range desc.Table.KeySchema {
+			if key.KeyType == kt {
+				keys = append(keys, aws.ToString(key.AttributeName))
+			}
+		}
+	}
+
+	avItems, err := ScanAllDynamoDB(t, client, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]map[string]any, 0, len(avItems))
+	if err := attributevalue.UnmarshalListOfMaps(avItems, &items); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal items of table %s: %w", tableName, err)
+	}
+	sortItemsByKey(items, keys)
+
+	return items, nil
+}
+
+// sortItemsByKey sorts items by the values of the key attributes, compared in order. Key attributes
+// are scalars, i.e. strings, numbers (float64) or binary values ([]byte).
+func sortItemsByKey(items []map[string]any, keys []string) {
+	slices.SortStableFunc(items, func(a, b map[string]any) int {
+		for _, key := range keys {
+			if c := compareKeyValues(a[key], b[key]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// compareKeyValues compares two key attribute values of the same type. Values of differing or
+// unsupported types compare by their formatted representation, which keeps the order stable.
+func compareKeyValues(a, b any) int {
+	switch av := a.(type) {
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv)
+		}
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return cmp.Compare(av, bv)
+		}
+	case []byte:
+		if bv, ok := b.([]byte); ok {
+			return bytes.Compare(av, bv)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// GetDynamoDBStreamRecords reads every record currently available in the stream identified by
+// streamArn. Each shard is read from TRIM_HORIZON until a GetRecords call returns no more records.
+func GetDynamoDBStreamRecords(t testing.TB, streamsClient *dynamodbstreams.Client, streamArn string) ([]streamtypes.Record, error) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	stream, err := streamsClient.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+		StreamArn: aws.String(streamArn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe stream %s: %w", streamArn, err)
+	}
+
+	var records []streamtypes.Record
+	for _, shard := range stream.StreamDescription.Shards {
+		iter, err := streamsClient.GetShardIterator(ctx, &dynamodbstreams.GetShardIteratorInput{
+			StreamArn:         aws.String(streamArn),
+			ShardId:           shard.ShardId,
+			ShardIteratorType: streamtypes.ShardIteratorTypeTrimHorizon,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get iterator for shard %s: %w", aws.ToString(shard.ShardId), err)
+		}
+
+		next := iter.ShardIterator
+		for next != nil {
+			out, err := streamsClient.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+				ShardIterator: next,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get records from shard %s: %w", aws.ToString(shard.ShardId), err)
+			}
+			if len(out.Records) == 0 {
+				break
+			}
+			records = append(records, out.Records...)
+			next = out.NextShardIterator
+		}
+	}
+
+	return records, nil
+}
+
 // DeleteDynamoDBTable deletes the specified DynamoDB table.
 // It's useful for cleanup after tests.
 func DeleteDynamoDBTable(t testing.TB, client *dynamodb.Client, tableName string) error {
@@ -2,13 +2,21 @@ package dynamodb_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/ory/dockertest/v3"
 	dynamodbtest "github.com/vvatanabe/dockertestx/dynamodb"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDynamoDB(t *testing.T) {
@@ -129,3 +137,666 @@ func TestDynamoDBWithOptions(t *testing.T) {
 		t.Fatalf("Failed to list tables: %v", err)
 	}
 }
+
+// createIDTable creates a table keyed by the string attribute "ID", as used by several tests below.
+func createIDTable(t *testing.T, client *dynamodb.Client, tableName string, opts ...dynamodbtest.TableOption) {
+	t.Helper()
+
+	keySchema := []types.KeySchemaElement{
+		{
+			AttributeName: aws.String("ID"),
+			KeyType:       types.KeyTypeHash,
+		},
+	}
+	attrDefs := []types.AttributeDefinition{
+		{
+			AttributeName: aws.String("ID"),
+			AttributeType: types.ScalarAttributeTypeS,
+		},
+	}
+
+	if err := dynamodbtest.CreateDynamoDBTable(t, client, tableName, keySchema, attrDefs, opts...); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+}
+
+// TestDynamoDBPayPerRequest demonstrates creating a table with on-demand billing
+func TestDynamoDBPayPerRequest(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "OnDemandTable"
+
+	createIDTable(t, client, tableName, dynamodbtest.WithBillingMode(types.BillingModePayPerRequest))
+
+	resp, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		t.Fatalf("Failed to describe table: %v", err)
+	}
+
+	if resp.Table.BillingModeSummary == nil {
+		t.Fatal("Expected BillingModeSummary to be set")
+	}
+	if got := resp.Table.BillingModeSummary.BillingMode; got != types.BillingModePayPerRequest {
+		t.Errorf("Expected billing mode %s, got %s", types.BillingModePayPerRequest, got)
+	}
+}
+
+// TestDynamoDBBatchItems demonstrates seeding more items than fit in a single BatchWriteItem call
+func TestDynamoDBBatchItems(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "BatchTable"
+	createIDTable(t, client, tableName)
+
+	// Seed 60 items, which requires three batches
+	const itemCount = 60
+	items := make([]map[string]types.AttributeValue, 0, itemCount)
+	for i := 0; i < itemCount; i++ {
+		items = append(items, map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: fmt.Sprintf("item-%02d", i)},
+		})
+	}
+
+	if err := dynamodbtest.PrepDynamoDBItems(t, client, tableName, items); err != nil {
+		t.Fatalf("Failed to insert items: %v", err)
+	}
+
+	// Verify all items are present
+	resp, err := client.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+		Select:    types.SelectCount,
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan table: %v", err)
+	}
+	if resp.Count != itemCount {
+		t.Errorf("Expected %d items, got %d", itemCount, resp.Count)
+	}
+}
+
+// fakeBatchWriter reports the first item of each call as unprocessed a fixed number of times.
+type fakeBatchWriter struct {
+	unprocessedCalls int
+	calls            int
+	written          map[string]bool
+}
+
+func (f *fakeBatchWriter) BatchWriteItem(_ context.Context, params *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	f.calls++
+	out := &dynamodb.BatchWriteItemOutput{}
+	for table, requests := range params.RequestItems {
+		for i, req := range requests {
+			if i == 0 && f.unprocessedCalls > 0 {
+				f.unprocessedCalls--
+				out.UnprocessedItems = map[string][]types.WriteRequest{table: {req}}
+				continue
+			}
+			id := req.PutRequest.Item["ID"].(*types.AttributeValueMemberS).Value
+			f.written[id] = true
+		}
+	}
+	return out, nil
+}
+
+// TestBatchWriteRetriesUnprocessed verifies that unprocessed items are retried until written
+func TestBatchWriteRetriesUnprocessed(t *testing.T) {
+	writer := &fakeBatchWriter{unprocessedCalls: 3, written: map[string]bool{}}
+
+	requests := make([]types.WriteRequest, 0, 30)
+	for i := 0; i < 30; i++ {
+		requests = append(requests, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+				"ID": &types.AttributeValueMemberS{Value: fmt.Sprintf("item-%02d", i)},
+			}},
+		})
+	}
+
+	if err := dynamodbtest.BatchWrite(context.Background(), writer, "FakeTable", requests); err != nil {
+		t.Fatalf("BatchWrite failed: %v", err)
+	}
+
+	if len(writer.written) != len(requests) {
+		t.Errorf("Expected %d items written, got %d", len(requests), len(writer.written))
+	}
+	// Two chunks plus three retries of unprocessed items
+	if writer.calls != 5 {
+		t.Errorf("Expected 5 BatchWriteItem calls, got %d", writer.calls)
+	}
+}
+
+// TestDynamoDBTTL demonstrates enabling TTL on a table
+func TestDynamoDBTTL(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "TTLTable"
+	createIDTable(t, client, tableName)
+
+	if err := dynamodbtest.EnableDynamoDBTTL(t, client, tableName, "ExpiresAt"); err != nil {
+		t.Fatalf("Failed to enable TTL: %v", err)
+	}
+
+	resp, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		t.Fatalf("Failed to describe TTL: %v", err)
+	}
+
+	desc := resp.TimeToLiveDescription
+	if desc == nil || desc.TimeToLiveStatus != types.TimeToLiveStatusEnabled {
+		t.Fatalf("Expected TTL status %s, got %+v", types.TimeToLiveStatusEnabled, desc)
+	}
+	if aws.ToString(desc.AttributeName) != "ExpiresAt" {
+		t.Errorf("Expected TTL attribute 'ExpiresAt', got '%s'", aws.ToString(desc.AttributeName))
+	}
+}
+
+// TestClearDynamoDBTable demonstrates emptying a table between subtests without dropping it
+func TestClearDynamoDBTable(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "ClearTable"
+	createIDTable(t, client, tableName)
+
+	items := make([]map[string]types.AttributeValue, 0, 30)
+	for i := 0; i < 30; i++ {
+		items = append(items, map[string]types.AttributeValue{
+			"ID":   &types.AttributeValueMemberS{Value: fmt.Sprintf("item-%02d", i)},
+			"Name": &types.AttributeValueMemberS{Value: "name"},
+		})
+	}
+	if err := dynamodbtest.PrepDynamoDBItems(t, client, tableName, items); err != nil {
+		t.Fatalf("Failed to insert items: %v", err)
+	}
+
+	if err := dynamodbtest.ClearDynamoDBTable(t, client, tableName); err != nil {
+		t.Fatalf("Failed to clear table: %v", err)
+	}
+
+	resp, err := client.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan table: %v", err)
+	}
+	if resp.Count != 0 {
+		t.Errorf("Expected an empty table, got %d items", resp.Count)
+	}
+}
+
+// TestCreateDynamoDBTableWaitsForActive verifies that a table can be written to right after creation
+func TestCreateDynamoDBTableWaitsForActive(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "ActiveTable"
+	createIDTable(t, client, tableName, dynamodbtest.WithActiveTimeout(time.Minute))
+
+	// Write immediately; this must not fail with ResourceNotFoundException
+	_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item: map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: "1"},
+		},
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		t.Fatalf("Table was not ready after creation: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Failed to put item: %v", err)
+	}
+}
+
+// TestDynamoDBEndpoint demonstrates building a separate client from the returned endpoint
+func TestDynamoDBEndpoint(t *testing.T) {
+	client, endpoint, cleanup := dynamodbtest.RunWithEndpoint(t, nil)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "EndpointTable"
+	createIDTable(t, client, tableName)
+
+	// Build a second client from the endpoint values only
+	other := dynamodb.New(dynamodb.Options{
+		Region:       endpoint.Region,
+		BaseEndpoint: aws.String(endpoint.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider(endpoint.AccessKeyID, endpoint.SecretAccessKey, ""),
+	})
+
+	resp, err := other.ListTables(ctx, &dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatalf("Failed to list tables with the second client: %v", err)
+	}
+	if len(resp.TableNames) != 1 || resp.TableNames[0] != tableName {
+		t.Errorf("Expected tables [%s], got %v", tableName, resp.TableNames)
+	}
+}
+
+// TestQueryDynamoDB demonstrates querying items by partition key and scanning a whole table
+func TestQueryDynamoDB(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	// Create a table with a partition key and a sort key
+	tableName := "Orders"
+	keySchema := []types.KeySchemaElement{
+		{AttributeName: aws.String("CustomerID"), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String("OrderID"), KeyType: types.KeyTypeRange},
+	}
+	attrDefs := []types.AttributeDefinition{
+		{AttributeName: aws.String("CustomerID"), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String("OrderID"), AttributeType: types.ScalarAttributeTypeS},
+	}
+	if err := dynamodbtest.CreateDynamoDBTable(t, client, tableName, keySchema, attrDefs); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// Three orders for alice, one for bob
+	var items []map[string]types.AttributeValue
+	for _, order := range []struct{ customer, order string }{
+		{"alice", "o1"}, {"alice", "o2"}, {"alice", "o3"}, {"bob", "o4"},
+	} {
+		items = append(items, map[string]types.AttributeValue{
+			"CustomerID": &types.AttributeValueMemberS{Value: order.customer},
+			"OrderID":    &types.AttributeValueMemberS{Value: order.order},
+		})
+	}
+	if err := dynamodbtest.PrepDynamoDBItems(t, client, tableName, items); err != nil {
+		t.Fatalf("Failed to insert items: %v", err)
+	}
+
+	got, err := dynamodbtest.QueryDynamoDB(t, client, tableName, "CustomerID = :c", map[string]types.AttributeValue{
+		":c": &types.AttributeValueMemberS{Value: "alice"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to query table: %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("Expected 3 orders for alice, got %d", len(got))
+	}
+
+	all, err := dynamodbtest.ScanAllDynamoDB(t, client, tableName)
+	if err != nil {
+		t.Fatalf("Failed to scan table: %v", err)
+	}
+	if len(all) != len(items) {
+		t.Errorf("Expected %d items in total, got %d", len(items), len(all))
+	}
+}
+
+// TestCreateTableFromStruct demonstrates creating a table from a struct with hash and range keys
+func TestCreateTableFromStruct(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	type Order struct {
+		CustomerID string  `dynamodbav:"customer_id" dynamodbkey:"hash"`
+		OrderID    int64   `dynamodbav:"order_id" dynamodbkey:"range"`
+		Total      float64 `dynamodbav:"total"`
+	}
+
+	ctx := context.Background()
+	tableName := "StructOrders"
+	if err := dynamodbtest.CreateTableFromStruct(t, client, tableName, Order{}); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// Round-trip an item marshaled from the same struct
+	item, err := attributevalue.MarshalMap(Order{CustomerID: "alice", OrderID: 1, Total: 9.5})
+	if err != nil {
+		t.Fatalf("Failed to marshal order: %v", err)
+	}
+	if err := dynamodbtest.PrepDynamoDBItems(t, client, tableName, []map[string]types.AttributeValue{item}); err != nil {
+		t.Fatalf("Failed to insert item: %v", err)
+	}
+
+	resp, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		t.Fatalf("Failed to describe table: %v", err)
+	}
+	if len(resp.Table.KeySchema) != 2 {
+		t.Fatalf("Expected 2 key schema elements, got %d", len(resp.Table.KeySchema))
+	}
+}
+
+// TestKeySchemaFromStruct verifies key schema derivation and error reporting without a container
+func TestKeySchemaFromStruct(t *testing.T) {
+	type Valid struct {
+		ID      string `dynamodbav:"id,omitempty" dynamodbkey:"hash"`
+		Version int    `dynamodbkey:"range"`
+		Payload []byte `dynamodbav:"payload"`
+	}
+
+	keySchema, attrDefs, err := dynamodbtest.KeySchemaFromStruct(&Valid{})
+	if err != nil {
+		t.Fatalf("KeySchemaFromStruct failed: %v", err)
+	}
+	if aws.ToString(keySchema[0].AttributeName) != "id" || keySchema[0].KeyType != types.KeyTypeHash {
+		t.Errorf("Unexpected hash key: %+v", keySchema[0])
+	}
+	if aws.ToString(keySchema[1].AttributeName) != "Version" || keySchema[1].KeyType != types.KeyTypeRange {
+		t.Errorf("Unexpected range key: %+v", keySchema[1])
+	}
+	if attrDefs[1].AttributeType != types.ScalarAttributeTypeN {
+		t.Errorf("Expected Version to be a number attribute, got %s", attrDefs[1].AttributeType)
+	}
+
+	type Unsupported struct {
+		ID time.Time `dynamodbkey:"hash"`
+	}
+	if _, _, err := dynamodbtest.KeySchemaFromStruct(Unsupported{}); err == nil {
+		t.Error("Expected an error for an unsupported key type")
+	}
+
+	type NoHash struct {
+		ID string `dynamodbkey:"range"`
+	}
+	if _, _, err := dynamodbtest.KeySchemaFromStruct(NoHash{}); err == nil {
+		t.Error("Expected an error for a struct without a hash key")
+	}
+}
+
+// TestDynamoDBStreams demonstrates reading the stream record produced by a write
+func TestDynamoDBStreams(t *testing.T) {
+	client, endpoint, cleanup := dynamodbtest.RunWithEndpoint(t, nil)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "StreamTable"
+	createIDTable(t, client, tableName, dynamodbtest.WithStream(types.StreamViewTypeNewAndOldImages))
+
+	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		t.Fatalf("Failed to describe table: %v", err)
+	}
+	streamArn := aws.ToString(desc.Table.LatestStreamArn)
+	if streamArn == "" {
+		t.Fatal("Expected the table to have a stream")
+	}
+
+	item := map[string]types.AttributeValue{
+		"ID": &types.AttributeValueMemberS{Value: "streamed"},
+	}
+	if err := dynamodbtest.PrepDynamoDBItems(t, client, tableName, []map[string]types.AttributeValue{item}); err != nil {
+		t.Fatalf("Failed to insert item: %v", err)
+	}
+
+	records, err := dynamodbtest.GetDynamoDBStreamRecords(t, dynamodbtest.NewStreamsClient(endpoint), streamArn)
+	if err != nil {
+		t.Fatalf("Failed to read stream records: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 stream record, got %d", len(records))
+	}
+	if records[0].EventName != "INSERT" {
+		t.Errorf("Expected an INSERT record, got %s", records[0].EventName)
+	}
+}
+
+// TestDynamoDBSharedDB demonstrates that clients with different credentials and regions share data in shared-db mode
+func TestDynamoDBSharedDB(t *testing.T) {
+	client, endpoint, cleanup := dynamodbtest.RunWithEndpoint(t, []func(*dockertest.RunOptions){
+		dynamodbtest.WithSharedDB(),
+	})
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "SharedTable"
+	createIDTable(t, client, tableName)
+
+	// A client with other credentials and another region sees the same database
+	other := dynamodb.New(dynamodb.Options{
+		Region:       "eu-west-1",
+		BaseEndpoint: aws.String(endpoint.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("otherkey", "othersecret", ""),
+	})
+
+	resp, err := other.ListTables(ctx, &dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatalf("Failed to list tables with the second client: %v", err)
+	}
+	if len(resp.TableNames) != 1 || resp.TableNames[0] != tableName {
+		t.Errorf("Expected tables [%s], got %v", tableName, resp.TableNames)
+	}
+}
+
+func TestPrepDynamoDBFromJSON(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	tableName := "JSONUsers"
+	keySchema := []types.KeySchemaElement{
+		{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash},
+	}
+	attrDefs := []types.AttributeDefinition{
+		{AttributeName: aws.String("ID"), AttributeType: types.ScalarAttributeTypeS},
+	}
+	if err := dynamodbtest.CreateDynamoDBTable(t, client, tableName, keySchema, attrDefs); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	f, err := os.Open("testdata/users.json")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	if err := dynamodbtest.PrepDynamoDBFromJSON(t, client, tableName, f); err != nil {
+		t.Fatalf("PrepDynamoDBFromJSON failed: %v", err)
+	}
+
+	resp, err := client.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key:       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to get item: %v", err)
+	}
+
+	var user struct {
+		ID      string
+		Name    string
+		Age     int
+		Active  bool
+		Tags    []string `dynamodbav:",stringset"`
+		Address map[string]string
+	}
+	if err := attributevalue.UnmarshalMap(resp.Item, &user); err != nil {
+		t.Fatalf("Failed to unmarshal item: %v", err)
+	}
+	if user.Name != "Alice" || user.Age != 30 || !user.Active || len(user.Tags) != 2 || user.Address["City"] != "Tokyo" {
+		t.Errorf("Unexpected item: %+v", user)
+	}
+}
+
+func TestDecodeJSONItems(t *testing.T) {
+	typed, err := os.Open("testdata/users.json")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer typed.Close()
+	plain, err := os.Open("testdata/users_plain.json")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer plain.Close()
+
+	typedItems, err := dynamodbtest.DecodeJSONItems(typed, false)
+	if err != nil {
+		t.Fatalf("Failed to decode DynamoDB JSON: %v", err)
+	}
+	plainItems, err := dynamodbtest.DecodeJSONItems(plain, true)
+	if err != nil {
+		t.Fatalf("Failed to decode plain JSON: %v", err)
+	}
+	if len(typedItems) != 2 || len(plainItems) != 2 {
+		t.Fatalf("Expected 2 items each, got %d and %d", len(typedItems), len(plainItems))
+	}
+
+	// The fixtures describe the same users; only the string set has no plain JSON equivalent.
+	delete(typedItems[0], "Tags")
+	for i := range typedItems {
+		if !reflect.DeepEqual(typedItems[i], plainItems[i]) {
+			t.Errorf("Item %d differs:\ntyped: %#v\nplain: %#v", i, typedItems[i], plainItems[i])
+		}
+	}
+
+	if _, err := dynamodbtest.DecodeJSONItems(strings.NewReader(`[{"ID": {"X": "1"}}]`), false); err == nil {
+		t.Error("Expected an error for an unknown type descriptor")
+	}
+}
+
+// TestPrepDynamoDBTransaction writes two items in one transaction and verifies that a transaction
+// whose condition fails leaves the table unchanged.
+func TestPrepDynamoDBTransaction(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "TransactionTable"
+	createIDTable(t, client, tableName)
+
+	putIfAbsent := func(id string) types.TransactWriteItem {
+		return types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(tableName),
+				Item:                map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+				ConditionExpression: aws.String("attribute_not_exists(ID)"),
+			},
+		}
+	}
+
+	if err := dynamodbtest.PrepDynamoDBTransaction(t, client, []types.TransactWriteItem{
+		putIfAbsent("order-1"),
+		putIfAbsent("payment-1"),
+	}); err != nil {
+		t.Fatalf("PrepDynamoDBTransaction failed: %v", err)
+	}
+
+	// order-1 already exists, so payment-2 must not be written either
+	err := dynamodbtest.PrepDynamoDBTransaction(t, client, []types.TransactWriteItem{
+		putIfAbsent("payment-2"),
+		putIfAbsent("order-1"),
+	})
+	if err == nil {
+		t.Fatal("Expected the transaction to be canceled")
+	}
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		t.Errorf("Expected a TransactionCanceledException, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "item 1: ConditionalCheckFailed") {
+		t.Errorf("Expected the error to name the failed condition, got %v", err)
+	}
+
+	resp, err := client.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(tableName),
+		Select:    types.SelectCount,
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan table: %v", err)
+	}
+	if resp.Count != 2 {
+		t.Errorf("Expected 2 items, got %d", resp.Count)
+	}
+}
+
+func TestCountDynamoDBItems(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	tableName := "CountTable"
+	createIDTable(t, client, tableName)
+
+	count, err := dynamodbtest.CountDynamoDBItems(t, client, tableName)
+	if err != nil {
+		t.Fatalf("CountDynamoDBItems failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected an empty table, got %d items", count)
+	}
+
+	items := make([]map[string]types.AttributeValue, 0, 10)
+	for i := 0; i < 10; i++ {
+		items = append(items, map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: fmt.Sprintf("item-%02d", i)},
+		})
+	}
+	if err := dynamodbtest.PrepDynamoDBItems(t, client, tableName, items); err != nil {
+		t.Fatalf("Failed to insert items: %v", err)
+	}
+
+	count, err = dynamodbtest.CountDynamoDBItems(t, client, tableName)
+	if err != nil {
+		t.Fatalf("CountDynamoDBItems failed: %v", err)
+	}
+	if count != 10 {
+		t.Errorf("Expected 10 items, got %d", count)
+	}
+}
+
+func TestExportDynamoDBTable(t *testing.T) {
+	client, cleanup := dynamodbtest.Run(t)
+	defer cleanup()
+
+	tableName := "ExportTable"
+	createIDTable(t, client, tableName)
+
+	f, err := os.Open("testdata/users_plain.json")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+	if err := dynamodbtest.PrepDynamoDBFromJSON(t, client, tableName, f, dynamodbtest.WithPlainJSON()); err != nil {
+		t.Fatalf("PrepDynamoDBFromJSON failed: %v", err)
+	}
+
+	items, err := dynamodbtest.ExportDynamoDBTable(t, client, tableName)
+	if err != nil {
+		t.Fatalf("ExportDynamoDBTable failed: %v", err)
+	}
+	got, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to encode export: %v", err)
+	}
+
+	want, err := os.ReadFile("testdata/users_export.golden.json")
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if string(got) != strings.TrimSpace(string(want)) {
+		t.Errorf("Export does not match the golden file:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSortItemsByKey(t *testing.T) {
+	items := []map[string]any{
+		{"PK": "b", "SK": float64(1)},
+		{"PK": "a", "SK": float64(10)},
+		{"PK": "a", "SK": float64(2)},
+		{"PK": "b", "SK": float64(0)},
+	}
+	dynamodbtest.SortItemsByKey(items, []string{"PK", "SK"})
+
+	want := []map[string]any{
+		{"PK": "a", "SK": float64(2)},
+		{"PK": "a", "SK": float64(10)},
+		{"PK": "b", "SK": float64(0)},
+		{"PK": "b", "SK": float64(1)},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Expected %v, got %v", want, items)
+	}
+}
@@ -0,0 +1,13 @@
+package dynamodb
+
+// BatchWrite exposes batchWrite to the external test package.
+var BatchWrite = batchWrite
+
+// KeySchemaFromStruct exposes keySchemaFromStruct to the external test package.
+var KeySchemaFromStruct = keySchemaFromStruct
+
+// DecodeJSONItems exposes decodeJSONItems to the external test package.
+var DecodeJSONItems = decodeJSONItems
+
+// SortItemsByKey exposes sortItemsByKey to the external test package.
+var SortItemsByKey = sortItemsByKey
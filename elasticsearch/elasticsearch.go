@@ -0,0 +1,186 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"testing"
+	"time"
+)
+
+const (
+	defaultElasticsearchImage = "docker.elastic.co/elasticsearch/elasticsearch"
+	defaultElasticsearchTag   = "8.15.3"
+	defaultElasticsearchPort  = "9200/tcp"
+	// With a 512MB heap, loading the bundled modules, including machine learning, is slow.
+	defaultElasticsearchMaxWait = 3 * time.Minute
+)
+
+// Run starts an Elasticsearch Docker container using the default settings and returns a connected
+// *elasticsearch.Client along with a cleanup function. It uses the default Elasticsearch image
+// ("docker.elastic.co/elasticsearch/elasticsearch") with tag "8.15.3". For more customization, use RunWithOptions.
+func Run(t testing.TB) (*elasticsearch.Client, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts an Elasticsearch Docker container using Docker and returns a connected
+// *elasticsearch.Client along with a cleanup function. It applies the default settings:
+//   - Repository: "docker.elastic.co/elasticsearch/elasticsearch"
+//   - Tag: "8.15.3"
+//   - Environment: single-node discovery with security disabled and a 512MB heap
+//
+// The container is considered ready once the cluster health endpoint reports yellow or green.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*elasticsearch.Client, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+	pool.MaxWait = defaultElasticsearchMaxWait
+
+	// Set default run options for Elasticsearch
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultElasticsearchImage,
+		Tag:        defaultElasticsearchTag,
+		Env: []string{
+			"discovery.type=single-node",
+			"xpack.security.enabled=false",
+			"ES_JAVA_OPTS=-Xms512m -Xmx512m",
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start elasticsearch container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultElasticsearchPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the elasticsearch container")
+	}
+	t.Logf("elasticsearch container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure elasticsearch container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{fmt.Sprintf("http://%s", actualPort)},
+	})
+	if err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to create elasticsearch client: %s", err)
+	}
+
+	// Wait until the cluster reports yellow or green
+	if err = settings.Retry(pool, func() error {
+		res, err := client.Cluster.Health(
+			client.Cluster.Health.WithWaitForStatus("yellow"),
+			client.Cluster.Health.WithTimeout(5*time.Second),
+		)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.IsError() {
+			return fmt.Errorf("cluster health request failed: %s", res.Status())
+		}
+		var health struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+			return err
+		}
+		if health.Status != "yellow" && health.Status != "green" {
+			return fmt.Errorf("cluster status is %q", health.Status)
+		}
+		return nil
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to elasticsearch: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove elasticsearch container: %s", err)
+		}
+	}
+
+	return client, cleanup
+}
+
+// PrepIndex sets up test data in an Elasticsearch index.
+// It bulk-indexes the given documents and refreshes the index so that they are immediately searchable.
+// If the request or any individual document fails, it returns an error.
+func PrepIndex(t testing.TB, client *elasticsearch.Client, index string, docs []any) error {
+	t.Helper()
+
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	for i, doc := range docs {
+		body.WriteString(`{"index":{}}` + "\n")
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to marshal document %d: %w", i, err)
+		}
+		body.Write(b)
+		body.WriteByte('\n')
+	}
+
+	res, err := client.Bulk(&body,
+		client.Bulk.WithIndex(index),
+		client.Bulk.WithRefresh("true"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to bulk index documents into '%s': %w", index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("failed to bulk index documents into '%s': %s", index, res.String())
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if result.Errors {
+		for i, item := range result.Items {
+			for _, op := range item {
+				if len(op.Error) > 0 {
+					return fmt.Errorf("failed to index document %d into '%s': %s", i, index, op.Error)
+				}
+			}
+		}
+		return fmt.Errorf("failed to bulk index documents into '%s'", index)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package elasticsearch_test
+
+import (
+	"encoding/json"
+	"github.com/ory/dockertest/v3"
+	estest "github.com/vvatanabe/dockertestx/elasticsearch"
+	"strings"
+	"testing"
+)
+
+type article struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// TestDefaultElasticsearch demonstrates indexing documents and running a match query.
+func TestDefaultElasticsearch(t *testing.T) {
+	// Start an Elasticsearch container with default options.
+	client, cleanup := estest.Run(t)
+	defer cleanup()
+
+	docs := []any{
+		article{Title: "Docker basics", Body: "containers make testing easy"},
+		article{Title: "Go testing", Body: "table driven tests in go"},
+		article{Title: "Search engines", Body: "full text search with elasticsearch"},
+	}
+	if err := estest.PrepIndex(t, client, "articles", docs); err != nil {
+		t.Fatalf("PrepIndex failed: %v", err)
+	}
+
+	// Run a match query against the body field
+	query := `{"query":{"match":{"body":"testing"}}}`
+	res, err := client.Search(
+		client.Search.WithIndex("articles"),
+		client.Search.WithBody(strings.NewReader(query)),
+	)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		t.Fatalf("search returned an error: %s", res.String())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source article `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode search response: %v", err)
+	}
+
+	if len(result.Hits.Hits) != 1 {
+		t.Fatalf("expected 1 hit, got %d", len(result.Hits.Hits))
+	}
+	if got := result.Hits.Hits[0].Source.Title; got != "Docker basics" {
+		t.Errorf("expected title 'Docker basics', got '%s'", got)
+	}
+}
+
+// TestElasticsearchWithCustomRunOptions demonstrates overriding default RunOptions.
+func TestElasticsearchWithCustomRunOptions(t *testing.T) {
+	// Custom RunOption to override the default tag
+	customTag := func(opts *dockertest.RunOptions) {
+		opts.Tag = "8.14.3"
+	}
+
+	client, cleanup := estest.RunWithOptions(t, []func(*dockertest.RunOptions){customTag})
+	defer cleanup()
+
+	res, err := client.Info()
+	if err != nil {
+		t.Fatalf("failed to get cluster info: %v", err)
+	}
+	defer res.Body.Close()
+
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatalf("failed to decode info response: %v", err)
+	}
+	if info.Version.Number != "8.14.3" {
+		t.Errorf("expected version 8.14.3, got %s", info.Version.Number)
+	}
+}
@@ -0,0 +1,153 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	defaultEtcdImage = "quay.io/coreos/etcd"
+	defaultEtcdTag   = "v3.5.21"
+	defaultEtcdPort  = "2379/tcp"
+)
+
+// Run starts an etcd Docker container using the default settings and returns a connected
+// *clientv3.Client along with a cleanup function. It uses the default etcd image
+// ("quay.io/coreos/etcd") with tag "v3.5.21". For more customization, use RunWithOptions.
+func Run(t testing.TB) (*clientv3.Client, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts an etcd Docker container using Docker and returns a connected
+// *clientv3.Client along with a cleanup function. It applies the default settings:
+//   - Repository: "quay.io/coreos/etcd"
+//   - Tag: "v3.5.21"
+//   - Cmd: a single-member etcd listening for clients on 0.0.0.0:2379
+//
+// The container is considered ready once its /health endpoint reports healthy.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*clientv3.Client, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	// Set default run options for etcd
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultEtcdImage,
+		Tag:        defaultEtcdTag,
+		Cmd: []string{
+			"etcd",
+			"--name", "dockertestx",
+			"--data-dir", "/etcd-data",
+			"--listen-client-urls", "http://0.0.0.0:2379",
+			"--advertise-client-urls", "http://0.0.0.0:2379",
+		},
+		ExposedPorts: []string{defaultEtcdPort},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start etcd container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultEtcdPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the etcd container")
+	}
+	t.Logf("etcd container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure etcd container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	endpoint := fmt.Sprintf("http://%s", actualPort)
+
+	// Wait until the health endpoint responds
+	if err = settings.Retry(pool, func() error {
+		return checkHealth(endpoint)
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to etcd: %s", err)
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{endpoint},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to create etcd client: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := client.Close(); err != nil {
+			t.Logf("failed to close etcd client: %s", err)
+		}
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove etcd container: %s", err)
+		}
+	}
+
+	return client, cleanup
+}
+
+// checkHealth queries etcd's /health endpoint and returns an error unless it reports healthy.
+func checkHealth(endpoint string) error {
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(endpoint + "/health")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var health struct {
+		Health string `json:"health"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return err
+	}
+	if health.Health != "true" {
+		return fmt.Errorf("etcd is not healthy yet (status code %d)", resp.StatusCode)
+	}
+	return nil
+}
+
+// PrepKV sets up test data in etcd.
+// It puts every key/value pair of the given map. If any put fails, it returns an error.
+func PrepKV(t testing.TB, client *clientv3.Client, kv map[string]string) error {
+	t.Helper()
+
+	ctx := context.Background()
+	for k, v := range kv {
+		if _, err := client.Put(ctx, k, v); err != nil {
+			return fmt.Errorf("failed to put key '%s': %w", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package etcd_test
+
+import (
+	"context"
+	etcdtest "github.com/vvatanabe/dockertestx/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"testing"
+	"time"
+)
+
+// TestDefaultEtcd demonstrates seeding keys and reading them back by prefix.
+func TestDefaultEtcd(t *testing.T) {
+	// Start an etcd container with default options.
+	client, cleanup := etcdtest.Run(t)
+	defer cleanup()
+
+	kv := map[string]string{
+		"/config/a": "1",
+		"/config/b": "2",
+		"/config/c": "3",
+	}
+	if err := etcdtest.PrepKV(t, client, kv); err != nil {
+		t.Fatalf("PrepKV failed: %v", err)
+	}
+
+	resp, err := client.Get(context.Background(), "/config/", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatalf("failed to get keys: %v", err)
+	}
+	if len(resp.Kvs) != len(kv) {
+		t.Fatalf("expected %d keys, got %d", len(kv), len(resp.Kvs))
+	}
+	for _, got := range resp.Kvs {
+		if want := kv[string(got.Key)]; string(got.Value) != want {
+			t.Errorf("expected value '%s' for key '%s', got '%s'", want, got.Key, got.Value)
+		}
+	}
+}
+
+// TestEtcdWatch demonstrates observing a put through a watch.
+func TestEtcdWatch(t *testing.T) {
+	client, cleanup := etcdtest.Run(t)
+	defer cleanup()
+
+	if err := etcdtest.PrepKV(t, client, map[string]string{"/leader": "node-1"}); err != nil {
+		t.Fatalf("PrepKV failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Start watching before the update
+	watch := client.Watch(ctx, "/leader")
+
+	if _, err := client.Put(ctx, "/leader", "node-2"); err != nil {
+		t.Fatalf("failed to put key: %v", err)
+	}
+
+	select {
+	case resp := <-watch:
+		if err := resp.Err(); err != nil {
+			t.Fatalf("watch failed: %v", err)
+		}
+		if len(resp.Events) != 1 {
+			t.Fatalf("expected 1 event, got %d", len(resp.Events))
+		}
+		if got := string(resp.Events[0].Kv.Value); got != "node-2" {
+			t.Errorf("expected value 'node-2', got '%s'", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for watch event")
+	}
+}
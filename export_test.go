@@ -0,0 +1,15 @@
+package dockertestx
+
+import "github.com/vvatanabe/dockertestx/internal"
+
+// DisconnectReaper drops the connection to the reaper as an exiting test process would,
+// and stops labeling new containers with the session.
+func DisconnectReaper() {
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+	if reaper.conn != nil {
+		_ = reaper.conn.Close()
+		reaper.conn = nil
+	}
+	internal.DeleteSessionLabel(SessionLabel)
+}
@@ -0,0 +1,70 @@
+package gorm
+
+import (
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/sql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+)
+
+// OpenMySQL starts a MySQL Docker container with sql.RunMySQL and returns a *gorm.DB using the
+// MySQL dialector along with a cleanup function. For more customization, use OpenMySQLWithOptions.
+func OpenMySQL(t testing.TB) (*gorm.DB, func()) {
+	return OpenMySQLWithOptions(t, nil)
+}
+
+// OpenMySQLWithOptions starts a MySQL Docker container with sql.RunMySQLWithOptions, passing runOpts
+// and hostOpts through, and returns a *gorm.DB using the MySQL dialector along with a cleanup function.
+func OpenMySQLWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*gorm.DB, func()) {
+	t.Helper()
+
+	db, cleanup := sql.RunMySQLWithOptions(t, runOpts, hostOpts...)
+	return open(t, mysql.New(mysql.Config{Conn: db}), cleanup)
+}
+
+// OpenPostgres starts a PostgreSQL Docker container with sql.RunPostgres and returns a *gorm.DB using the
+// PostgreSQL dialector along with a cleanup function. For more customization, use OpenPostgresWithOptions.
+func OpenPostgres(t testing.TB) (*gorm.DB, func()) {
+	return OpenPostgresWithOptions(t, nil)
+}
+
+// OpenPostgresWithOptions starts a PostgreSQL Docker container with sql.RunPostgresWithOptions, passing runOpts
+// and hostOpts through, and returns a *gorm.DB using the PostgreSQL dialector along with a cleanup function.
+func OpenPostgresWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*gorm.DB, func()) {
+	t.Helper()
+
+	db, cleanup := sql.RunPostgresWithOptions(t, runOpts, hostOpts...)
+	return open(t, postgres.New(postgres.Config{Conn: db}), cleanup)
+}
+
+// open wraps the connection behind dialector in a *gorm.DB. The returned cleanup function is the one
+// of the underlying container, which also closes the connection.
+func open(t testing.TB, dialector gorm.Dialector, cleanup func()) (*gorm.DB, func()) {
+	t.Helper()
+
+	gdb, err := gorm.Open(dialector, &gorm.Config{
+		// Query logging is left to the test; errors are returned from each call.
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to open gorm DB: %s", err)
+	}
+	return gdb, cleanup
+}
+
+// AutoMigrate runs gorm's AutoMigrate for the given models, creating or updating their tables.
+// If the migration fails, it returns an error.
+func AutoMigrate(t testing.TB, gdb *gorm.DB, models ...any) error {
+	t.Helper()
+
+	if err := gdb.AutoMigrate(models...); err != nil {
+		return fmt.Errorf("failed to auto-migrate models: %w", err)
+	}
+	return nil
+}
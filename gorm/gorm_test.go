@@ -0,0 +1,54 @@
+package gorm_test
+
+import (
+	"github.com/vvatanabe/dockertestx/gorm"
+	"testing"
+)
+
+type user struct {
+	ID    uint
+	Name  string
+	Email string `gorm:"uniqueIndex;size:255"`
+}
+
+func TestOpenMySQL(t *testing.T) {
+	gdb, cleanup := gorm.OpenMySQL(t)
+	defer cleanup()
+
+	if err := gorm.AutoMigrate(t, gdb, &user{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+
+	if err := gdb.Create(&user{Name: "Alice", Email: "alice@example.com"}).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	var got user
+	if err := gdb.Where("email = ?", "alice@example.com").First(&got).Error; err != nil {
+		t.Fatalf("failed to retrieve user: %v", err)
+	}
+	if got.Name != "Alice" {
+		t.Errorf("expected name 'Alice', but got '%s'", got.Name)
+	}
+}
+
+func TestOpenPostgres(t *testing.T) {
+	gdb, cleanup := gorm.OpenPostgres(t)
+	defer cleanup()
+
+	if err := gorm.AutoMigrate(t, gdb, &user{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+
+	if err := gdb.Create(&user{Name: "Bob", Email: "bob@example.com"}).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	var got user
+	if err := gdb.Where("email = ?", "bob@example.com").First(&got).Error; err != nil {
+		t.Fatalf("failed to retrieve user: %v", err)
+	}
+	if got.Name != "Bob" {
+		t.Errorf("expected name 'Bob', but got '%s'", got.Name)
+	}
+}
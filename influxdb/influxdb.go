@@ -0,0 +1,140 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"testing"
+	"time"
+)
+
+const (
+	defaultInfluxDBImage = "influxdb"
+	defaultInfluxDBTag   = "2"
+	defaultInfluxDBPort  = "8086/tcp"
+	defaultOrg           = "test"
+	defaultBucket        = "test"
+	defaultToken         = "test-token"
+)
+
+// Run starts an InfluxDB 2 Docker container using the default settings and returns a connected
+// influxdb2.Client along with a cleanup function. It uses the default InfluxDB image ("influxdb")
+// with tag "2", an organization and a bucket both named "test". For more customization, use RunWithOptions.
+func Run(t testing.TB) (influxdb2.Client, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts an InfluxDB 2 Docker container using Docker and returns a connected
+// influxdb2.Client along with a cleanup function. It applies the default settings:
+//   - Repository: "influxdb"
+//   - Tag: "2"
+//   - Environment: DOCKER_INFLUXDB_INIT_MODE=setup with organization "test", bucket "test" and admin token "test-token"
+//
+// The client authenticates with DOCKER_INFLUXDB_INIT_ADMIN_TOKEN. The container is considered ready once
+// the health endpoint passes and the initial organization exists.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (influxdb2.Client, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	// Set default run options for InfluxDB
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultInfluxDBImage,
+		Tag:        defaultInfluxDBTag,
+		Env: []string{
+			"DOCKER_INFLUXDB_INIT_MODE=setup",
+			"DOCKER_INFLUXDB_INIT_USERNAME=admin",
+			"DOCKER_INFLUXDB_INIT_PASSWORD=adminpassword",
+			"DOCKER_INFLUXDB_INIT_ORG=" + defaultOrg,
+			"DOCKER_INFLUXDB_INIT_BUCKET=" + defaultBucket,
+			"DOCKER_INFLUXDB_INIT_ADMIN_TOKEN=" + defaultToken,
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	org := internal.GetEnvValue(defaultRunOpts.Env, "DOCKER_INFLUXDB_INIT_ORG")
+	token := internal.GetEnvValue(defaultRunOpts.Env, "DOCKER_INFLUXDB_INIT_ADMIN_TOKEN")
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start influxdb container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultInfluxDBPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the influxdb container")
+	}
+	t.Logf("influxdb container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure influxdb container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	client := influxdb2.NewClient(fmt.Sprintf("http://%s", actualPort), token)
+
+	// The image runs its setup against a temporary server before starting the real one, so wait
+	// until the real server is healthy and knows the initial organization.
+	if err = settings.Retry(pool, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		health, err := client.Health(ctx)
+		if err != nil {
+			return err
+		}
+		if health.Status != "pass" {
+			return fmt.Errorf("health status is %q", health.Status)
+		}
+		_, err = client.OrganizationsAPI().FindOrganizationByName(ctx, org)
+		return err
+	}); err != nil {
+		logs.Dump()
+		client.Close()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to influxdb: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		client.Close()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove influxdb container: %s", err)
+		}
+	}
+
+	return client, cleanup
+}
+
+// PrepPoints sets up test data by writing the given points to the bucket of the organization.
+// The write is blocking, so the points can be queried as soon as it returns.
+// If the points cannot be written, it returns an error.
+func PrepPoints(t testing.TB, client influxdb2.Client, org, bucket string, points []*write.Point) error {
+	t.Helper()
+
+	if len(points) == 0 {
+		return nil
+	}
+
+	if err := client.WriteAPIBlocking(org, bucket).WritePoint(context.Background(), points...); err != nil {
+		return fmt.Errorf("failed to write points to bucket '%s': %w", bucket, err)
+	}
+	return nil
+}
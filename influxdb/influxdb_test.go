@@ -0,0 +1,48 @@
+package influxdb_test
+
+import (
+	"context"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	influxtest "github.com/vvatanabe/dockertestx/influxdb"
+	"testing"
+	"time"
+)
+
+// TestDefaultInfluxDB demonstrates writing points and reading them back with a Flux query.
+func TestDefaultInfluxDB(t *testing.T) {
+	// Start an InfluxDB container with default options.
+	client, cleanup := influxtest.Run(t)
+	defer cleanup()
+
+	now := time.Now()
+	points := []*write.Point{
+		influxdb2.NewPoint("cpu", map[string]string{"host": "a"}, map[string]any{"usage": 10.0}, now.Add(-2*time.Minute)),
+		influxdb2.NewPoint("cpu", map[string]string{"host": "a"}, map[string]any{"usage": 20.0}, now.Add(-time.Minute)),
+		influxdb2.NewPoint("cpu", map[string]string{"host": "b"}, map[string]any{"usage": 99.0}, now.Add(-time.Minute)),
+	}
+	if err := influxtest.PrepPoints(t, client, "test", "test", points); err != nil {
+		t.Fatalf("PrepPoints failed: %v", err)
+	}
+
+	query := `from(bucket: "test")
+		|> range(start: -1h)
+		|> filter(fn: (r) => r._measurement == "cpu" and r.host == "a" and r._field == "usage")
+		|> sum()`
+	result, err := client.QueryAPI("test").Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer result.Close()
+
+	var sums []float64
+	for result.Next() {
+		sums = append(sums, result.Record().Value().(float64))
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("failed to read query result: %v", err)
+	}
+	if len(sums) != 1 || sums[0] != 30 {
+		t.Errorf("expected a sum of 30 for host a, got %v", sums)
+	}
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"net/url"
+)
+
+// HostAddress returns the host and port on which containerPort of resource can be reached from the
+// test process. When the Docker daemon is reached over TCP (e.g. DOCKER_HOST=tcp://192.168.99.100:2376),
+// published ports live on that machine, so the host of the daemon endpoint is used. Otherwise the
+// IP the port is bound to is used, with wildcard addresses mapped to "localhost".
+func HostAddress(pool *dockertest.Pool, resource *dockertest.Resource, containerPort string) (string, string, error) {
+	port := resource.GetPort(containerPort)
+	if port == "" {
+		return "", "", fmt.Errorf("no host port was assigned for container port %s", containerPort)
+	}
+
+	if host := daemonHost(pool.Client.Endpoint()); host != "" {
+		return host, port, nil
+	}
+
+	host := resource.GetBoundIP(containerPort)
+	if host == "::" {
+		host = "localhost"
+	}
+	return host, port, nil
+}
+
+// daemonHost returns the host name of a TCP Docker endpoint, or an empty string for local
+// endpoints such as unix sockets and named pipes.
+func daemonHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "tcp", "http", "https":
+		return u.Hostname()
+	default:
+		return ""
+	}
+}
@@ -1,13 +1,100 @@
 package internal
 
+import (
+	"net"
+	"strconv"
+)
+
 // GetEnvValue searches the given slice of environment variable strings for the specified key
 // and returns its value. If the key is not found, it returns an empty string.
+// Use LookupEnvValue to tell a missing key from an empty value.
 func GetEnvValue(env []string, key string) string {
+	v, _ := LookupEnvValue(env, key)
+	return v
+}
+
+// GetEnvValueOr is like GetEnvValue, but returns def if the key is not found.
+// A key that is present with an empty value returns the empty value.
+func GetEnvValueOr(env []string, key, def string) string {
+	if v, ok := LookupEnvValue(env, key); ok {
+		return v
+	}
+	return def
+}
+
+// LookupEnvValue searches the given slice of environment variable strings for the specified key.
+// If the key is present, it returns its value, which may be empty, and true. Otherwise it returns
+// an empty string and false. Like GetEnvValue, it reports the first occurrence of the key.
+func LookupEnvValue(env []string, key string) (string, bool) {
 	prefix := key + "="
 	for _, v := range env {
 		if len(v) >= len(prefix) && v[:len(prefix)] == prefix {
-			return v[len(prefix):]
+			return v[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+// UnquoteEnvValues returns a copy of env in which the values of the given keys are stripped of one
+// pair of matching double or single quotes surrounding them, so that both KEY="value" and
+// KEY='value' become KEY=value. Within double quotes, Go escape sequences such as \" are
+// interpreted; single-quoted values are taken literally. This only loosely resembles shell-style
+// assignment: there is no variable expansion, and quotes that do not enclose the whole value are
+// kept. Docker passes values to the container verbatim, so the result replaces the options' Env
+// (see dockertestx.WithUnquotedEnv), keeping the container and the client in agreement on the
+// credentials. Only the first occurrence of each key is rewritten, as GetEnvValue reads only that.
+func UnquoteEnvValues(env []string, keys ...string) []string {
+	out := make([]string, len(env))
+	copy(out, env)
+	for _, key := range keys {
+		prefix := key + "="
+		for i, v := range out {
+			if len(v) >= len(prefix) && v[:len(prefix)] == prefix {
+				out[i] = prefix + unquote(v[len(prefix):])
+				break
+			}
+		}
+	}
+	return out
+}
+
+// unquote removes one pair of matching quotes surrounding v. If v is not quoted, or a double-quoted
+// v is not a valid Go string literal, it returns v unchanged.
+func unquote(v string) string {
+	if len(v) < 2 || v[0] != v[len(v)-1] {
+		return v
+	}
+	switch v[0] {
+	case '\'':
+		return v[1 : len(v)-1]
+	case '"':
+		if u, err := strconv.Unquote(v); err == nil {
+			return u
+		}
+	}
+	return v
+}
+
+// FreePort asks the kernel for a free TCP port on the loopback interface and returns it.
+// It is used by services that must advertise their host port before the container starts.
+func FreePort() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// GetCmdFlagValue searches the given command arguments for a flag in the form "--name=value"
+// and returns the value of its last occurrence. If the flag is not found, it returns an empty string.
+func GetCmdFlagValue(cmd []string, flag string) string {
+	prefix := flag + "="
+	value := ""
+	for _, arg := range cmd {
+		if len(arg) >= len(prefix) && arg[:len(prefix)] == prefix {
+			value = arg[len(prefix):]
 		}
 	}
-	return ""
+	return value
 }
@@ -1,6 +1,9 @@
 package internal
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestGetEnvValue(t *testing.T) {
 	// Test case 1: Key exists in the environment slice.
@@ -53,3 +56,148 @@ func TestGetEnvValue(t *testing.T) {
 		t.Errorf("GetEnvValue(env2, %q) = %q; want %q", "MY_VARIABLE", got, want)
 	}
 }
+
+func TestLookupEnvValue(t *testing.T) {
+	env := []string{
+		"MYSQL_ROOT_PASSWORD=",
+		"MYSQL_DATABASE=test",
+	}
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{key: "MYSQL_DATABASE", want: "test", wantOK: true},
+		{key: "MYSQL_ROOT_PASSWORD", want: "", wantOK: true},
+		{key: "MYSQL_USER", want: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := LookupEnvValue(env, tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("LookupEnvValue(env, %q) = (%q, %v); want (%q, %v)", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGetEnvValueOr(t *testing.T) {
+	env := []string{
+		"MYSQL_ROOT_PASSWORD=",
+		"MYSQL_DATABASE=test",
+	}
+
+	tests := []struct {
+		key  string
+		def  string
+		want string
+	}{
+		{key: "MYSQL_DATABASE", def: "other", want: "test"},
+		// Present but empty keys keep their empty value.
+		{key: "MYSQL_ROOT_PASSWORD", def: "secret", want: ""},
+		{key: "MYSQL_USER", def: "root", want: "root"},
+	}
+	for _, tt := range tests {
+		if got := GetEnvValueOr(env, tt.key, tt.def); got != tt.want {
+			t.Errorf("GetEnvValueOr(env, %q, %q) = %q; want %q", tt.key, tt.def, got, tt.want)
+		}
+	}
+	if got := GetEnvValueOr(nil, "ANY_VAR", "fallback"); got != "fallback" {
+		t.Errorf("GetEnvValueOr(nil, %q, %q) = %q; want %q", "ANY_VAR", "fallback", got, "fallback")
+	}
+}
+
+func TestUnquoteEnvValues(t *testing.T) {
+	env := []string{
+		"EQUALS=p@ss=word",
+		`DOUBLE="p@ss=word"`,
+		`SINGLE='it''s'`,
+		`ESCAPED="say \"hi\""`,
+		`EMPTY_QUOTED=""`,
+		"EMPTY=",
+		`PARTIAL="abc`,
+		`INNER=a"b"c`,
+		`LONE="`,
+		`UNLISTED="kept"`,
+		`DOUBLE="second"`,
+	}
+	original := append([]string(nil), env...)
+
+	got := UnquoteEnvValues(env, "EQUALS", "DOUBLE", "SINGLE", "ESCAPED", "EMPTY_QUOTED", "EMPTY", "PARTIAL", "INNER", "LONE", "MISSING")
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "EQUALS", want: "p@ss=word"},
+		{key: "DOUBLE", want: "p@ss=word"},
+		{key: "SINGLE", want: "it''s"},
+		{key: "ESCAPED", want: `say "hi"`},
+		{key: "EMPTY_QUOTED", want: ""},
+		{key: "EMPTY", want: ""},
+		{key: "PARTIAL", want: `"abc`},
+		{key: "INNER", want: `a"b"c`},
+		{key: "LONE", want: `"`},
+		{key: "UNLISTED", want: `"kept"`},
+		{key: "MISSING", want: ""},
+	}
+	for _, tt := range tests {
+		if v := GetEnvValue(got, tt.key); v != tt.want {
+			t.Errorf("GetEnvValue(UnquoteEnvValues(env), %q) = %q; want %q", tt.key, v, tt.want)
+		}
+	}
+
+	if len(got) != len(env) {
+		t.Fatalf("UnquoteEnvValues() returned %d entries; want %d", len(got), len(env))
+	}
+	if last := got[len(got)-1]; last != `DOUBLE="second"` {
+		t.Errorf("later occurrence of DOUBLE = %q; want it unchanged", last)
+	}
+	for i := range env {
+		if env[i] != original[i] {
+			t.Errorf("env[%d] was modified to %q; want %q", i, env[i], original[i])
+		}
+	}
+}
+
+func TestFreePort(t *testing.T) {
+	port, err := FreePort()
+	if err != nil {
+		t.Fatalf("FreePort() returned error: %v", err)
+	}
+	if port == "" || port == "0" {
+		t.Errorf("FreePort() = %q; want a non-zero port", port)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{logf: func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}}
+
+	_, _ = w.Write([]byte("first\nsec"))
+	_, _ = w.Write([]byte("ond\nthird"))
+	w.flush()
+
+	want := []string{"[container] first", "[container] second", "[container] third"}
+	if len(lines) != len(want) {
+		t.Fatalf("lineWriter produced %d lines; want %d (%q)", len(lines), len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q; want %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestGetCmdFlagValue(t *testing.T) {
+	cmd := []string{"--character-set-server=latin1", "--skip-name-resolve", "--character-set-server=utf8mb4"}
+
+	// The last occurrence wins, as it does for mysqld.
+	if got := GetCmdFlagValue(cmd, "--character-set-server"); got != "utf8mb4" {
+		t.Errorf("GetCmdFlagValue(cmd, %q) = %q; want %q", "--character-set-server", got, "utf8mb4")
+	}
+	if got := GetCmdFlagValue(cmd, "--collation-server"); got != "" {
+		t.Errorf("GetCmdFlagValue(cmd, %q) = %q; want empty string", "--collation-server", got)
+	}
+}
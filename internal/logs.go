@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"sync"
+)
+
+// LogCapture surfaces the stdout/stderr of a container according to the DumpLogs and
+// FollowLogs settings. The zero value and a nil *LogCapture do nothing.
+type LogCapture struct {
+	logf        func(format string, args ...any)
+	pool        *dockertest.Pool
+	containerID string
+	dump        bool
+
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// CaptureLogs starts following the logs of resource into logf if s.FollowLogs is set.
+// The returned LogCapture must be stopped with Dump (on failure) or Stop (on cleanup).
+func CaptureLogs(logf func(format string, args ...any), pool *dockertest.Pool, resource *dockertest.Resource, s *Settings) *LogCapture {
+	c := &LogCapture{
+		logf:        logf,
+		pool:        pool,
+		containerID: resource.Container.ID,
+		// Followed logs have already been written, so they are not dumped a second time.
+		dump: s.DumpLogs && !s.FollowLogs,
+	}
+	if !s.FollowLogs {
+		return c
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		w := &lineWriter{logf: logf}
+		_ = pool.Client.Logs(docker.LogsOptions{
+			Context:      ctx,
+			Container:    c.containerID,
+			OutputStream: w,
+			ErrorStream:  w,
+			Stdout:       true,
+			Stderr:       true,
+			Follow:       true,
+		})
+		w.flush()
+	}()
+	return c
+}
+
+// Dump stops following and, if DumpLogs is set, writes the complete container logs into logf.
+// Run functions call it when the container fails to become ready.
+func (c *LogCapture) Dump() {
+	if c == nil {
+		return
+	}
+	c.Stop()
+	if !c.dump {
+		return
+	}
+	logs, err := FetchLogs(c.pool, c.containerID)
+	if err != nil {
+		c.logf("failed to fetch container logs: %s", err)
+		return
+	}
+	c.logf("container logs:\n%s", logs)
+}
+
+// FetchLogs returns the complete stdout and stderr of the container, interleaved as written.
+func FetchLogs(pool *dockertest.Pool, containerID string) (string, error) {
+	var buf bytes.Buffer
+	if err := pool.Client.Logs(docker.LogsOptions{
+		Container:    containerID,
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Stdout:       true,
+		Stderr:       true,
+	}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Stop stops following the container logs and waits until everything read so far has been written.
+// It must be called before the test finishes, because logging after that point panics.
+func (c *LogCapture) Stop() {
+	if c == nil || c.cancel == nil {
+		return
+	}
+	c.stopOnce.Do(func() {
+		c.cancel()
+		<-c.done
+	})
+}
+
+// lineWriter passes every complete line written to it to logf.
+type lineWriter struct {
+	mu   sync.Mutex
+	logf func(format string, args ...any)
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.logf("[container] %s", w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.logf("[container] %s", w.buf)
+		w.buf = nil
+	}
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"sync"
+
+	"github.com/ory/dockertest/v3"
+)
+
+var (
+	sessionMu     sync.Mutex
+	sessionLabels map[string]string
+)
+
+// SetSessionLabel registers a label that is added to every container started afterwards,
+// so that a reaper can find the containers of this test process.
+func SetSessionLabel(key, value string) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if sessionLabels == nil {
+		sessionLabels = make(map[string]string)
+	}
+	sessionLabels[key] = value
+}
+
+// DeleteSessionLabel removes a label registered with SetSessionLabel.
+func DeleteSessionLabel(key string) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	delete(sessionLabels, key)
+}
+
+// applySessionLabels adds the registered session labels to opts.
+func applySessionLabels(opts *dockertest.RunOptions) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if len(sessionLabels) == 0 {
+		return
+	}
+	if opts.Labels == nil {
+		opts.Labels = make(map[string]string, len(sessionLabels))
+	}
+	for k, v := range sessionLabels {
+		opts.Labels[k] = v
+	}
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"time"
+)
+
+// Retry behaves like (*dockertest.Pool).Retry, but stops as soon as ctx is done.
+// The wait between attempts is interrupted by cancellation, and the returned error wraps ctx.Err()
+// in that case.
+func Retry(ctx context.Context, pool *dockertest.Pool, op func() error) error {
+	if pool.MaxWait == 0 {
+		pool.MaxWait = time.Minute
+	}
+	bo := backoff.NewExponentialBackOff()
+	bo.MaxInterval = time.Second * 5
+	bo.MaxElapsedTime = pool.MaxWait
+	if err := backoff.Retry(op, backoff.WithContext(bo, ctx)); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("retry aborted: %w", ctxErr)
+		}
+		if bo.NextBackOff() == backoff.Stop {
+			return fmt.Errorf("reached retry deadline: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Backoff configures an exponential backoff between readiness attempts: the first wait is Initial,
+// and each following wait is Factor times the previous one, capped at Max.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+}
+
+// newBackOff returns a backoff.BackOff that waits as configured by b and gives up after maxElapsed.
+// Unlike the default of (*dockertest.Pool).Retry, the intervals are not randomized.
+func (b Backoff) newBackOff(maxElapsed time.Duration) *backoff.ExponentialBackOff {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = b.Initial
+	bo.MaxInterval = b.Max
+	bo.Multiplier = b.Factor
+	if bo.Multiplier < 1 {
+		bo.Multiplier = 1
+	}
+	if bo.MaxInterval < bo.InitialInterval {
+		bo.MaxInterval = bo.InitialInterval
+	}
+	bo.RandomizationFactor = 0
+	bo.MaxElapsedTime = maxElapsed
+	bo.Reset()
+	return bo
+}
+
+// RetryBackoff is like Retry, but waits between attempts as configured by b.
+func RetryBackoff(ctx context.Context, pool *dockertest.Pool, b Backoff, op func() error) error {
+	if pool.MaxWait == 0 {
+		pool.MaxWait = time.Minute
+	}
+	bo := b.newBackOff(pool.MaxWait)
+	if err := backoff.Retry(op, backoff.WithContext(bo, ctx)); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("retry aborted: %w", ctxErr)
+		}
+		if bo.NextBackOff() == backoff.Stop {
+			return fmt.Errorf("reached retry deadline: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// PullImage pulls the image referenced by opts unless it is already present, so that a long
+// pull can be cancelled through ctx. (*dockertest.Pool).RunWithOptions skips its own pull afterwards.
+func PullImage(ctx context.Context, pool *dockertest.Pool, opts *dockertest.RunOptions) error {
+	tag := opts.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	if _, err := pool.Client.InspectImage(fmt.Sprintf("%s:%s", opts.Repository, tag)); err == nil {
+		return nil
+	}
+	if err := pool.Client.PullImage(docker.PullImageOptions{
+		Repository: opts.Repository,
+		Tag:        tag,
+		Platform:   opts.Platform,
+		Context:    ctx,
+	}, opts.Auth); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("image pull aborted: %w", ctxErr)
+		}
+		return err
+	}
+	return nil
+}
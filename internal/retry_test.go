@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"github.com/ory/dockertest/v3"
+	"testing"
+	"time"
+)
+
+func TestRetrySucceeds(t *testing.T) {
+	pool := &dockertest.Pool{MaxWait: 10 * time.Second}
+	attempts := 0
+	err := Retry(context.Background(), pool, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Retry() returned error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Retry() made %d attempts; want 3", attempts)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	pool := &dockertest.Pool{MaxWait: time.Minute}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := Retry(ctx, pool, func() error {
+		return errors.New("never ready")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Retry() error = %v; want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Retry() took %s after cancellation; want it to return promptly", elapsed)
+	}
+}
+
+func TestBackoffIntervals(t *testing.T) {
+	bo := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Factor: 2}.newBackOff(time.Minute)
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := bo.NextBackOff(); got != w {
+			t.Errorf("interval %d = %s; want %s", i, got, w)
+		}
+	}
+}
+
+func TestRetryBackoffWaitsBetweenAttempts(t *testing.T) {
+	pool := &dockertest.Pool{MaxWait: 10 * time.Second}
+	b := Backoff{Initial: 20 * time.Millisecond, Max: 80 * time.Millisecond, Factor: 2}
+
+	var attempts []time.Time
+	err := RetryBackoff(context.Background(), pool, b, func() error {
+		attempts = append(attempts, time.Now())
+		if len(attempts) < 5 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RetryBackoff() returned error: %v", err)
+	}
+
+	want := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond, 80 * time.Millisecond}
+	for i, w := range want {
+		if got := attempts[i+1].Sub(attempts[i]); got < w {
+			t.Errorf("wait before attempt %d = %s; want at least %s", i+2, got, w)
+		}
+	}
+}
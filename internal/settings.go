@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Settings holds dockertestx settings that have no counterpart in dockertest.RunOptions.
+// They are attached to a *dockertest.RunOptions so that they can be configured through the
+// same RunOption functions ([]func(*dockertest.RunOptions)) as the container itself.
+type Settings struct {
+	// ReadyTimeout bounds the total time spent waiting for a container to become ready.
+	// Zero means the package default.
+	ReadyTimeout time.Duration
+	// DumpLogs writes the container logs into the test log when the container fails to start.
+	DumpLogs bool
+	// FollowLogs streams the container logs into the test log until cleanup.
+	FollowLogs bool
+	// ResourceDst receives the started container's resource, if set.
+	ResourceDst **dockertest.Resource
+	// NetworkAliases are the names under which the container can be reached by other containers
+	// on its user-defined networks.
+	NetworkAliases []string
+	// Backoff replaces the default wait between readiness attempts, if set.
+	Backoff *Backoff
+	// DBPool configures the connection pool of the *sql.DB returned by the sql package, if set.
+	DBPool *DBPool
+	// RetryHook is called with the attempt number, starting at 1, and the error of each failed
+	// readiness attempt, if set.
+	RetryHook func(attempt int, err error)
+}
+
+// DBPool holds the connection pool settings of a *sql.DB.
+type DBPool struct {
+	MaxOpen     int
+	MaxIdle     int
+	MaxLifetime time.Duration
+}
+
+// Retry calls op until it succeeds or pool.MaxWait has elapsed, like (*dockertest.Pool).Retry.
+// Run functions use it to wait for readiness so that Backoff is honored.
+func (s *Settings) Retry(pool *dockertest.Pool, op func() error) error {
+	return s.RetryContext(context.Background(), pool, op)
+}
+
+// RetryContext is like Retry, but stops as soon as ctx is done.
+func (s *Settings) RetryContext(ctx context.Context, pool *dockertest.Pool, op func() error) error {
+	if hook := s.RetryHook; hook != nil {
+		attempt := 0
+		inner := op
+		op = func() error {
+			attempt++
+			err := inner()
+			if err != nil {
+				hook(attempt, err)
+			}
+			return err
+		}
+	}
+	if s.Backoff == nil {
+		return Retry(ctx, pool, op)
+	}
+	return RetryBackoff(ctx, pool, *s.Backoff, op)
+}
+
+// OnStart applies the settings that need the started container. Run functions call it as soon as
+// the container has been created.
+func (s *Settings) OnStart(pool *dockertest.Pool, resource *dockertest.Resource) error {
+	s.ExposeResource(resource)
+	return s.connectAliases(pool, resource)
+}
+
+// ExposeResource stores resource in ResourceDst, if one was requested.
+func (s *Settings) ExposeResource(resource *dockertest.Resource) {
+	if s.ResourceDst != nil {
+		*s.ResourceDst = resource
+	}
+}
+
+// connectAliases reconnects the container to each of its user-defined networks with NetworkAliases.
+// dockertest connects the networks when it creates the container but cannot set aliases.
+func (s *Settings) connectAliases(pool *dockertest.Pool, resource *dockertest.Resource) error {
+	if len(s.NetworkAliases) == 0 {
+		return nil
+	}
+
+	id := resource.Container.ID
+	for name, endpoint := range resource.Container.NetworkSettings.Networks {
+		// Aliases are only supported on user-defined networks.
+		if name == "bridge" || name == "host" || name == "none" {
+			continue
+		}
+		if err := pool.Client.DisconnectNetwork(endpoint.NetworkID, docker.NetworkConnectionOptions{
+			Container: id,
+		}); err != nil {
+			return fmt.Errorf("failed to disconnect from network %s: %w", name, err)
+		}
+		if err := pool.Client.ConnectNetwork(endpoint.NetworkID, docker.NetworkConnectionOptions{
+			Container:      id,
+			EndpointConfig: &docker.EndpointConfig{Aliases: s.NetworkAliases},
+		}); err != nil {
+			return fmt.Errorf("failed to connect to network %s: %w", name, err)
+		}
+	}
+
+	// Reconnecting may change the container's addresses on the networks.
+	container, err := pool.Client.InspectContainer(id)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container %s: %w", id, err)
+	}
+	resource.Container = container
+	return nil
+}
+
+// settings maps a *dockertest.RunOptions to its *Settings.
+var settings sync.Map
+
+// SettingsOf returns the Settings attached to opts, attaching new zero Settings if there are none.
+// RunOption functions use it to record settings.
+func SettingsOf(opts *dockertest.RunOptions) *Settings {
+	v, _ := settings.LoadOrStore(opts, &Settings{})
+	return v.(*Settings)
+}
+
+// TakeSettings detaches and returns the Settings attached to opts, or zero Settings if there are none.
+// Run functions call it once after applying all RunOption functions. It also adds the session labels
+// registered with SetSessionLabel to opts.
+func TakeSettings(opts *dockertest.RunOptions) *Settings {
+	applySessionLabels(opts)
+	v, ok := settings.LoadAndDelete(opts)
+	if !ok {
+		return &Settings{}
+	}
+	return v.(*Settings)
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+)
+
+func TestSettings(t *testing.T) {
+	opts := &dockertest.RunOptions{}
+
+	// Test case 1: Settings recorded through SettingsOf are returned by TakeSettings.
+	SettingsOf(opts).ReadyTimeout = time.Minute
+	if got := SettingsOf(opts).ReadyTimeout; got != time.Minute {
+		t.Errorf("SettingsOf(opts).ReadyTimeout = %s; want %s", got, time.Minute)
+	}
+	if got := TakeSettings(opts).ReadyTimeout; got != time.Minute {
+		t.Errorf("TakeSettings(opts).ReadyTimeout = %s; want %s", got, time.Minute)
+	}
+
+	// Test case 2: TakeSettings detaches the settings.
+	if got := TakeSettings(opts).ReadyTimeout; got != 0 {
+		t.Errorf("TakeSettings(opts).ReadyTimeout after take = %s; want 0", got)
+	}
+
+	// Test case 3: Settings are not shared between RunOptions.
+	SettingsOf(opts).ReadyTimeout = time.Second
+	if got := TakeSettings(&dockertest.RunOptions{}).ReadyTimeout; got != 0 {
+		t.Errorf("TakeSettings(other).ReadyTimeout = %s; want 0", got)
+	}
+	TakeSettings(opts)
+}
+
+func TestExposeResource(t *testing.T) {
+	// Test case 1: Without a destination, ExposeResource is a no-op.
+	(&Settings{}).ExposeResource(&dockertest.Resource{})
+
+	// Test case 2: The resource is stored in the destination.
+	var got *dockertest.Resource
+	want := &dockertest.Resource{}
+	(&Settings{ResourceDst: &got}).ExposeResource(want)
+	if got != want {
+		t.Errorf("ExposeResource did not store the resource")
+	}
+}
+
+func TestTakeSettingsAppliesSessionLabels(t *testing.T) {
+	SetSessionLabel("dockertestx.test", "1")
+	defer DeleteSessionLabel("dockertestx.test")
+
+	opts := &dockertest.RunOptions{Labels: map[string]string{"project": "x"}}
+	TakeSettings(opts)
+	if got := opts.Labels["dockertestx.test"]; got != "1" {
+		t.Errorf("Labels[dockertestx.test] = %q; want %q", got, "1")
+	}
+	if got := opts.Labels["project"]; got != "x" {
+		t.Errorf("Labels[project] = %q; want %q", got, "x")
+	}
+
+	DeleteSessionLabel("dockertestx.test")
+	opts = &dockertest.RunOptions{}
+	TakeSettings(opts)
+	if len(opts.Labels) != 0 {
+		t.Errorf("Labels = %v; want none", opts.Labels)
+	}
+}
+
+func TestSettingsRetryHook(t *testing.T) {
+	var attempts []int
+	var errs []error
+	s := &Settings{
+		Backoff: &Backoff{Initial: time.Millisecond, Max: time.Millisecond, Factor: 1},
+		RetryHook: func(attempt int, err error) {
+			attempts = append(attempts, attempt)
+			errs = append(errs, err)
+		},
+	}
+
+	notReady := errors.New("connection refused")
+	calls := 0
+	err := s.Retry(&dockertest.Pool{MaxWait: 10 * time.Second}, func() error {
+		calls++
+		if calls < 4 {
+			return fmt.Errorf("attempt %d: %w", calls, notReady)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Retry() returned error: %v", err)
+	}
+
+	// The hook is only called for the failed attempts.
+	if want := []int{1, 2, 3}; !slices.Equal(attempts, want) {
+		t.Errorf("RetryHook attempts = %v; want %v", attempts, want)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, notReady) {
+			t.Errorf("RetryHook error %d = %v; want it to wrap %v", i, err, notReady)
+		}
+	}
+}
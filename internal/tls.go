@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NewSelfSignedTLSConfig generates a self-signed certificate valid for "localhost", 127.0.0.1 and ::1
+// and returns a *tls.Config that both presents it (Certificates) and trusts it (RootCAs).
+// The same config can therefore be mounted into a server container and used to dial it.
+func NewSelfSignedTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost", Organization: []string{"dockertestx"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}},
+		RootCAs:      roots,
+		ServerName:   "localhost",
+	}, nil
+}
+
+// WriteTLSFiles writes the first certificate of cfg and its private key as PEM files
+// ("cert.pem", "key.pem") into dir, and the certificate chain as "ca.pem" so it can be used
+// as the CA file of a server. The files are world-readable because containers usually
+// run as a non-root user that does not own them.
+func WriteTLSFiles(dir string, cfg *tls.Config) (certFile, keyFile, caFile string, err error) {
+	if cfg == nil || len(cfg.Certificates) == 0 {
+		return "", "", "", errors.New("tls config has no certificates")
+	}
+	cert := cfg.Certificates[0]
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	var certPEM []byte
+	for _, der := range cert.Certificate {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	if err := os.Chmod(dir, 0o755); err != nil {
+		return "", "", "", fmt.Errorf("failed to change permissions of %s: %w", dir, err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	caFile = filepath.Join(dir, "ca.pem")
+	for path, data := range map[string][]byte{certFile: certPEM, keyFile: keyPEM, caFile: certPEM} {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return "", "", "", fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	return certFile, keyFile, caFile, nil
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSelfSignedTLSConfig(t *testing.T) {
+	cfg, err := NewSelfSignedTLSConfig()
+	if err != nil {
+		t.Fatalf("NewSelfSignedTLSConfig() error = %v", err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(cfg.Certificates))
+	}
+	if err := cfg.Certificates[0].Leaf.VerifyHostname("localhost"); err != nil {
+		t.Errorf("certificate is not valid for localhost: %v", err)
+	}
+
+	// Test case: The written files can be loaded back as a key pair.
+	dir := t.TempDir()
+	certFile, keyFile, caFile, err := WriteTLSFiles(dir, cfg)
+	if err != nil {
+		t.Fatalf("WriteTLSFiles() error = %v", err)
+	}
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		t.Errorf("failed to load written key pair: %v", err)
+	}
+	if filepath.Dir(caFile) != dir {
+		t.Errorf("expected CA file in %q, got %q", dir, caFile)
+	}
+	if _, err := os.Stat(caFile); err != nil {
+		t.Errorf("CA file was not written: %v", err)
+	}
+
+	// Test case: A config without certificates is rejected.
+	if _, _, _, err := WriteTLSFiles(dir, &tls.Config{}); err == nil {
+		t.Error("expected an error for a config without certificates")
+	}
+}
@@ -0,0 +1,218 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/segmentio/kafka-go"
+	"github.com/vvatanabe/dockertestx/internal"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	defaultKafkaImage = "apache/kafka"
+	defaultKafkaTag   = "3.8.0"
+	defaultKafkaPort  = "9092/tcp"
+	// The broker needs a while to elect itself as the KRaft controller.
+	defaultKafkaMaxWait = 2 * time.Minute
+)
+
+// Run starts a single-broker Kafka Docker container using the default settings and returns
+// the list of broker addresses along with a cleanup function. It uses the default Kafka image
+// ("apache/kafka") with tag "3.8.0" in KRaft mode. For more customization, use RunWithOptions.
+func Run(t testing.TB) ([]string, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts a single-broker Kafka Docker container using Docker and returns the list
+// of broker addresses along with a cleanup function. It applies the default settings:
+//   - Repository: "apache/kafka"
+//   - Tag: "3.8.0"
+//   - Environment: a combined broker/controller node running in KRaft mode
+//
+// Because Kafka returns its advertised listener to clients, the host port is reserved up front
+// and published explicitly, so that the advertised address is reachable from the test process.
+//
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) ([]string, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+	pool.MaxWait = defaultKafkaMaxWait
+
+	hostPort, err := internal.FreePort()
+	if err != nil {
+		t.Fatalf("failed to reserve a host port for kafka: %s", err)
+	}
+
+	// Set default run options for Kafka
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultKafkaImage,
+		Tag:        defaultKafkaTag,
+		Env: []string{
+			"KAFKA_NODE_ID=1",
+			"KAFKA_PROCESS_ROLES=broker,controller",
+			"KAFKA_LISTENERS=PLAINTEXT://:9092,CONTROLLER://:9093",
+			"KAFKA_ADVERTISED_LISTENERS=PLAINTEXT://localhost:" + hostPort,
+			"KAFKA_CONTROLLER_LISTENER_NAMES=CONTROLLER",
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP=CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS=1@localhost:9093",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR=1",
+			"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR=1",
+			"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR=1",
+			"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS=0",
+			"KAFKA_NUM_PARTITIONS=1",
+		},
+		ExposedPorts: []string{defaultKafkaPort},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			defaultKafkaPort: {{HostIP: "0.0.0.0", HostPort: hostPort}},
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start kafka container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultKafkaPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the kafka container")
+	}
+	t.Logf("kafka container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure kafka container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	brokers := []string{actualPort}
+
+	// Wait until the broker answers metadata requests
+	if err = settings.Retry(pool, func() error {
+		conn, err := kafka.Dial("tcp", actualPort)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = conn.Brokers()
+		return err
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to kafka: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove kafka container: %s", err)
+		}
+	}
+
+	return brokers, cleanup
+}
+
+// PrepTopic creates a topic with the given number of partitions and a replication factor of 1.
+// The request is sent to the cluster controller. If the topic cannot be created, it returns an error.
+func PrepTopic(t testing.TB, brokers []string, name string, partitions int) error {
+	t.Helper()
+
+	if len(brokers) == 0 {
+		return errors.New("no kafka brokers were given")
+	}
+
+	conn, err := kafka.Dial("tcp", brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to connect to kafka broker '%s': %w", brokers[0], err)
+	}
+	defer conn.Close()
+
+	controller, err := conn.Controller()
+	if err != nil {
+		return fmt.Errorf("failed to look up kafka controller: %w", err)
+	}
+
+	controllerConn, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to kafka controller: %w", err)
+	}
+	defer controllerConn.Close()
+
+	if err := controllerConn.CreateTopics(kafka.TopicConfig{
+		Topic:             name,
+		NumPartitions:     partitions,
+		ReplicationFactor: 1,
+	}); err != nil {
+		return fmt.Errorf("failed to create topic '%s': %w", name, err)
+	}
+	return nil
+}
+
+// ProduceMessages writes the given messages to the topic and waits until the broker acknowledges them.
+// If any message cannot be written, it returns an error.
+func ProduceMessages(t testing.TB, brokers []string, topic string, msgs ...kafka.Message) error {
+	t.Helper()
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer w.Close()
+
+	if err := w.WriteMessages(context.Background(), msgs...); err != nil {
+		return fmt.Errorf("failed to produce messages to topic '%s': %w", topic, err)
+	}
+	return nil
+}
+
+// ConsumeMessages reads n messages from the beginning of the topic, using a consumer group that is
+// unique to the calling test. It returns an error if fewer than n messages arrive within the timeout.
+func ConsumeMessages(t testing.TB, brokers []string, topic string, n int, timeout time.Duration) ([]kafka.Message, error) {
+	t.Helper()
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     fmt.Sprintf("dockertestx-%s-%d", t.Name(), time.Now().UnixNano()),
+		Topic:       topic,
+		StartOffset: kafka.FirstOffset,
+	})
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msgs := make([]kafka.Message, 0, n)
+	for len(msgs) < n {
+		msg, err := r.ReadMessage(ctx)
+		if err != nil {
+			return msgs, fmt.Errorf("failed to consume messages from topic '%s' (got %d of %d): %w", topic, len(msgs), n, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
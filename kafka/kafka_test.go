@@ -0,0 +1,75 @@
+package kafka_test
+
+import (
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/segmentio/kafka-go"
+	kafkatest "github.com/vvatanabe/dockertestx/kafka"
+	"testing"
+	"time"
+)
+
+// TestDefaultKafka demonstrates creating a topic, producing and consuming a message.
+func TestDefaultKafka(t *testing.T) {
+	// Start a Kafka container with default options.
+	brokers, cleanup := kafkatest.Run(t)
+	defer cleanup()
+
+	topic := "test-topic"
+	if err := kafkatest.PrepTopic(t, brokers, topic, 3); err != nil {
+		t.Fatalf("PrepTopic failed: %v", err)
+	}
+
+	// Produce a message
+	msg := kafka.Message{Key: []byte("key-1"), Value: []byte("hello kafka")}
+	if err := kafkatest.ProduceMessages(t, brokers, topic, msg); err != nil {
+		t.Fatalf("ProduceMessages failed: %v", err)
+	}
+
+	// Consume it back
+	got, err := kafkatest.ConsumeMessages(t, brokers, topic, 1, 30*time.Second)
+	if err != nil {
+		t.Fatalf("ConsumeMessages failed: %v", err)
+	}
+	if string(got[0].Key) != "key-1" {
+		t.Errorf("expected key 'key-1', got '%s'", got[0].Key)
+	}
+	if string(got[0].Value) != "hello kafka" {
+		t.Errorf("expected value 'hello kafka', got '%s'", got[0].Value)
+	}
+}
+
+// TestKafkaWithCustomHostOptions demonstrates providing host configuration options.
+func TestKafkaWithCustomHostOptions(t *testing.T) {
+	// Host option to set AutoRemove to true
+	autoRemove := func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+	}
+
+	brokers, cleanup := kafkatest.RunWithOptions(t, nil, autoRemove)
+	defer cleanup()
+
+	topic := "multi-topic"
+	if err := kafkatest.PrepTopic(t, brokers, topic, 1); err != nil {
+		t.Fatalf("PrepTopic failed: %v", err)
+	}
+
+	msgs := []kafka.Message{
+		{Value: []byte("first")},
+		{Value: []byte("second")},
+		{Value: []byte("third")},
+	}
+	if err := kafkatest.ProduceMessages(t, brokers, topic, msgs...); err != nil {
+		t.Fatalf("ProduceMessages failed: %v", err)
+	}
+
+	// A single partition preserves the order of the messages
+	got, err := kafkatest.ConsumeMessages(t, brokers, topic, len(msgs), 30*time.Second)
+	if err != nil {
+		t.Fatalf("ConsumeMessages failed: %v", err)
+	}
+	for i, msg := range msgs {
+		if string(got[i].Value) != string(msg.Value) {
+			t.Errorf("expected message %d to be '%s', got '%s'", i, msg.Value, got[i].Value)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package keycloak provides helpers to run a Keycloak server with an imported realm and to obtain
+// tokens from it, e.g. to test services that validate JWTs issued by Keycloak.
+package keycloak
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	defaultKeycloakImage = "quay.io/keycloak/keycloak"
+	defaultKeycloakTag   = "26.0"
+	defaultKeycloakPort  = "8080/tcp"
+	// Quarkus augments the server and the realm is imported before the OIDC endpoints respond.
+	defaultKeycloakMaxWait = 3 * time.Minute
+	importDir              = "/opt/keycloak/data/import"
+
+	// DefaultAdminUser and DefaultAdminPassword are the credentials of the temporary admin user
+	// in the master realm.
+	DefaultAdminUser     = "admin"
+	DefaultAdminPassword = "admin"
+)
+
+// httpClient is used for the readiness checks and token requests.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Run starts a Keycloak Docker container in dev mode that imports the realm described by
+// realmImportJSON, a realm representation as exported by Keycloak, and returns the base URL of the
+// server (e.g. "http://localhost:55001") along with a cleanup function. It uses the default Keycloak
+// image ("quay.io/keycloak/keycloak") with tag "26.0". For more customization, use RunWithOptions.
+func Run(t testing.TB, realmImportJSON string) (string, func()) {
+	return RunWithOptions(t, realmImportJSON, nil)
+}
+
+// RunWithOptions starts a Keycloak Docker container using Docker and returns the base URL of the
+// server along with a cleanup function. It applies the default settings:
+//   - Repository: "quay.io/keycloak/keycloak"
+//   - Tag: "26.0"
+//   - Command: ["start-dev", "--import-realm"]
+//   - Environment: KC_BOOTSTRAP_ADMIN_USERNAME=admin, KC_BOOTSTRAP_ADMIN_PASSWORD=admin
+//
+// realmImportJSON is mounted into the import directory of the container. The container is considered
+// ready once the OIDC discovery endpoint of the imported realm responds, which can take a minute on a
+// cold start.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, realmImportJSON string, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (string, func()) {
+	t.Helper()
+
+	var realm struct {
+		Realm string `json:"realm"`
+	}
+	if err := json.Unmarshal([]byte(realmImportJSON), &realm); err != nil || realm.Realm == "" {
+		t.Fatalf("realmImportJSON must be a realm representation with a \"realm\" name (error: %v)", err)
+	}
+
+	// Keycloak does not run as root, so the mounted directory must be readable by others.
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatalf("failed to make realm import directory readable: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, realm.Realm+"-realm.json"), []byte(realmImportJSON), 0o644); err != nil {
+		t.Fatalf("failed to write realm import file: %s", err)
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+	pool.MaxWait = defaultKeycloakMaxWait
+
+	// Set default run options for Keycloak
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultKeycloakImage,
+		Tag:        defaultKeycloakTag,
+		Cmd:        []string{"start-dev", "--import-realm"},
+		Env: []string{
+			"KC_BOOTSTRAP_ADMIN_USERNAME=" + DefaultAdminUser,
+			"KC_BOOTSTRAP_ADMIN_PASSWORD=" + DefaultAdminPassword,
+		},
+		Mounts:       []string{dir + ":" + importDir + ":ro"},
+		ExposedPorts: []string{defaultKeycloakPort},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start keycloak container: %s", err)
+	}
+
+	host, port, err := internal.HostAddress(pool, resource, defaultKeycloakPort)
+	if err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to get the address of the keycloak container: %s", err)
+	}
+	actualPort := net.JoinHostPort(host, port)
+	t.Logf("keycloak container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure keycloak container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	baseURL := fmt.Sprintf("http://%s", actualPort)
+
+	// Wait until the imported realm serves its OIDC discovery document
+	if err = settings.Retry(pool, func() error {
+		return checkDiscovery(baseURL, realm.Realm)
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to keycloak: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove keycloak container: %s", err)
+		}
+	}
+
+	return baseURL, cleanup
+}
+
+// checkDiscovery requests the OIDC discovery document of realm and returns an error unless it
+// responds with 200 OK.
+func checkDiscovery(baseURL, realm string) error {
+	resp, err := httpClient.Get(fmt.Sprintf("%s/realms/%s/.well-known/openid-configuration", baseURL, url.PathEscape(realm)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("realm %s is not ready yet (status %d)", realm, resp.StatusCode)
+	}
+	return nil
+}
+
+// GetToken obtains an access token for user from realm with the resource owner password grant and
+// returns it. clientID must be a public client of the realm with direct access grants enabled.
+// If the token request fails, it returns an error including the OAuth error description.
+func GetToken(t testing.TB, baseURL, realm, clientID, user, pass string) (string, error) {
+	t.Helper()
+
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {clientID},
+		"username":   {user},
+		"password":   {pass},
+		"scope":      {"openid"},
+	}
+	endpoint := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token", baseURL, url.PathEscape(realm))
+	resp, err := httpClient.Post(endpoint, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("token request for user '%s' failed with status %d: %s: %s", user, resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	return token.AccessToken, nil
+}
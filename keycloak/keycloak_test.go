@@ -0,0 +1,54 @@
+package keycloak_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/vvatanabe/dockertestx/keycloak"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestKeycloak imports a realm, obtains a token for its user and checks the issuer claim.
+func TestKeycloak(t *testing.T) {
+	realm, err := os.ReadFile("testdata/realm.json")
+	if err != nil {
+		t.Fatalf("failed to read realm: %v", err)
+	}
+
+	baseURL, cleanup := keycloak.Run(t, string(realm))
+	defer cleanup()
+
+	token, err := keycloak.GetToken(t, baseURL, "test", "test-client", "alice", "alice-password")
+	if err != nil {
+		t.Fatalf("GetToken failed: %v", err)
+	}
+
+	// The payload is the second, base64url encoded segment of the JWT
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT with 3 segments, got %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("failed to decode token payload: %v", err)
+	}
+	var claims struct {
+		Issuer            string `json:"iss"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("failed to unmarshal claims: %v", err)
+	}
+	if want := baseURL + "/realms/test"; claims.Issuer != want {
+		t.Errorf("expected issuer %s, got %s", want, claims.Issuer)
+	}
+	if claims.PreferredUsername != "alice" {
+		t.Errorf("expected preferred_username alice, got %s", claims.PreferredUsername)
+	}
+
+	// Wrong credentials are rejected
+	if _, err := keycloak.GetToken(t, baseURL, "test", "test-client", "alice", "wrong"); err == nil {
+		t.Error("expected an error for a wrong password")
+	}
+}
@@ -0,0 +1,390 @@
+package localstack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesistypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	defaultLocalStackImage = "localstack/localstack"
+	defaultLocalStackTag   = "3.8"
+	defaultEdgePort        = "4566/tcp"
+	defaultServices        = "sqs,sns"
+	defaultRegion          = "us-east-1"
+	defaultAccessKey       = "test"
+	defaultSecretKey       = "test"
+)
+
+// WithServices returns a RunOption that sets the comma separated SERVICES list LocalStack starts.
+// Run waits until every listed service reports itself as available.
+func WithServices(services ...string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		entry := "SERVICES=" + strings.Join(services, ",")
+		for i, e := range opts.Env {
+			if strings.HasPrefix(e, "SERVICES=") {
+				opts.Env[i] = entry
+				return
+			}
+		}
+		opts.Env = append(opts.Env, entry)
+	}
+}
+
+// Run starts a LocalStack Docker container with the given AWS services enabled and returns an
+// aws.Config pointed at the LocalStack edge endpoint along with a cleanup function.
+// If no services are given, SQS and SNS are started. For more customization, use RunWithOptions.
+func Run(t testing.TB, services ...string) (aws.Config, func()) {
+	if len(services) == 0 {
+		return RunWithOptions(t, nil)
+	}
+	return RunWithOptions(t, []func(*dockertest.RunOptions){WithServices(services...)})
+}
+
+// RunWithOptions starts a LocalStack Docker container using Docker and returns an aws.Config
+// pointed at the LocalStack edge endpoint along with a cleanup function. It applies the default settings:
+//   - Repository: "localstack/localstack"
+//   - Tag: "3.8"
+//   - Environment: SERVICES=sqs,sns
+//
+// The returned config uses the "us-east-1" region and static dummy credentials, so it can be
+// passed directly to any AWS SDK v2 service client constructor (e.g. sqs.NewFromConfig).
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (aws.Config, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	// Set default run options for LocalStack
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultLocalStackImage,
+		Tag:        defaultLocalStackTag,
+		Env: []string{
+			"SERVICES=" + defaultServices,
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	services := internal.GetEnvValue(defaultRunOpts.Env, "SERVICES")
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start localstack container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultEdgePort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the localstack container")
+	}
+	t.Logf("localstack container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure localstack container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	endpoint := fmt.Sprintf("http://%s", actualPort)
+
+	// Wait until every requested service is available
+	if err = settings.Retry(pool, func() error {
+		return checkHealth(endpoint, services)
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to localstack: %s", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion(defaultRegion),
+		config.WithBaseEndpoint(endpoint),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(defaultAccessKey, defaultSecretKey, "")),
+	)
+	if err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to load aws config: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove localstack container: %s", err)
+		}
+	}
+
+	return cfg, cleanup
+}
+
+// checkHealth queries LocalStack's health endpoint and returns an error unless every service in
+// the comma separated list reports "available" or "running".
+func checkHealth(endpoint, services string) error {
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(endpoint + "/_localstack/health")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code from health endpoint: %d", resp.StatusCode)
+	}
+
+	var health struct {
+		Services map[string]string `json:"services"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return err
+	}
+	for _, s := range strings.Split(services, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if status := health.Services[s]; status != "available" && status != "running" {
+			return fmt.Errorf("service %s is not ready yet (status %q)", s, status)
+		}
+	}
+	return nil
+}
+
+// PrepSQSQueue creates an SQS queue with the given name and returns its URL.
+// If the queue cannot be created, it returns an error.
+func PrepSQSQueue(t testing.TB, cfg aws.Config, name string) (string, error) {
+	t.Helper()
+
+	return createQueue(sqs.NewFromConfig(cfg), name, nil)
+}
+
+// PrepSQSFIFOQueue creates a FIFO SQS queue and returns its URL. The ".fifo" suffix SQS requires
+// is appended to name unless it is already present. Content-based deduplication is enabled, so
+// messages can be sent without a MessageDeduplicationId; a MessageGroupId is still required.
+// If the queue cannot be created, it returns an error.
+func PrepSQSFIFOQueue(t testing.TB, cfg aws.Config, name string) (string, error) {
+	t.Helper()
+
+	if !strings.HasSuffix(name, ".fifo") {
+		name += ".fifo"
+	}
+	return createQueue(sqs.NewFromConfig(cfg), name, map[string]string{
+		string(types.QueueAttributeNameFifoQueue):                 "true",
+		string(types.QueueAttributeNameContentBasedDeduplication): "true",
+	})
+}
+
+// PrepSQSQueueWithDLQ creates a dead-letter queue named dlqName and an SQS queue named name whose
+// RedrivePolicy moves a message to the dead-letter queue once it has been received more than
+// maxReceiveCount times without being deleted. It returns the URLs of both queues.
+// If either queue cannot be created, it returns an error.
+func PrepSQSQueueWithDLQ(t testing.TB, cfg aws.Config, name, dlqName string, maxReceiveCount int) (queueURL, dlqURL string, err error) {
+	t.Helper()
+
+	client := sqs.NewFromConfig(cfg)
+	dlqURL, err = createQueue(client, dlqName, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	attrs, err := client.GetQueueAttributes(context.Background(), &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(dlqURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get the ARN of queue '%s': %w", dlqName, err)
+	}
+
+	policy, err := json.Marshal(map[string]string{
+		"deadLetterTargetArn": attrs.Attributes[string(types.QueueAttributeNameQueueArn)],
+		"maxReceiveCount":     strconv.Itoa(maxReceiveCount),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode redrive policy: %w", err)
+	}
+
+	queueURL, err = createQueue(client, name, map[string]string{
+		string(types.QueueAttributeNameRedrivePolicy): string(policy),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return queueURL, dlqURL, nil
+}
+
+// createQueue creates an SQS queue with the given attributes and returns its URL.
+func createQueue(client *sqs.Client, name string, attributes map[string]string) (string, error) {
+	out, err := client.CreateQueue(context.Background(), &sqs.CreateQueueInput{
+		QueueName:  aws.String(name),
+		Attributes: attributes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create queue '%s': %w", name, err)
+	}
+	return aws.ToString(out.QueueUrl), nil
+}
+
+// PrepSNSTopic creates an SNS topic with the given name and returns its ARN.
+// If the topic cannot be created, it returns an error.
+func PrepSNSTopic(t testing.TB, cfg aws.Config, name string) (string, error) {
+	t.Helper()
+
+	client := sns.NewFromConfig(cfg)
+	out, err := client.CreateTopic(context.Background(), &sns.CreateTopicInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create topic '%s': %w", name, err)
+	}
+	return aws.ToString(out.TopicArn), nil
+}
+
+const (
+	// kinesisStreamTimeout bounds how long PrepKinesisStream waits for a stream to become ACTIVE.
+	kinesisStreamTimeout = 30 * time.Second
+	// maxPutRecords is the maximum number of records Kinesis accepts in one PutRecords call.
+	maxPutRecords = 500
+)
+
+// PrepKinesisStream creates a Kinesis stream with the given number of shards, waits until it is
+// ACTIVE and returns its ARN. LocalStack must be started with the "kinesis" service.
+// If the stream cannot be created or does not become ACTIVE within 30 seconds, it returns an error.
+func PrepKinesisStream(t testing.TB, cfg aws.Config, name string, shards int) (string, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), kinesisStreamTimeout)
+	defer cancel()
+
+	client := kinesis.NewFromConfig(cfg)
+	if _, err := client.CreateStream(ctx, &kinesis.CreateStreamInput{
+		StreamName: aws.String(name),
+		ShardCount: aws.Int32(int32(shards)),
+	}); err != nil {
+		return "", fmt.Errorf("failed to create stream '%s': %w", name, err)
+	}
+
+	waiter := kinesis.NewStreamExistsWaiter(client, func(o *kinesis.StreamExistsWaiterOptions) {
+		o.MinDelay = 200 * time.Millisecond
+		o.MaxDelay = time.Second
+	})
+	out, err := waiter.WaitForOutput(ctx, &kinesis.DescribeStreamInput{
+		StreamName: aws.String(name),
+	}, kinesisStreamTimeout)
+	if err != nil {
+		return "", fmt.Errorf("stream '%s' did not become active: %w", name, err)
+	}
+	return aws.ToString(out.StreamDescription.StreamARN), nil
+}
+
+// PutKinesisRecords writes each element of data as a record with the given partition key to the
+// specified stream. Records sharing a partition key land on the same shard in order.
+// If any record cannot be written, it returns an error.
+func PutKinesisRecords(t testing.TB, cfg aws.Config, streamName, partitionKey string, data [][]byte) error {
+	t.Helper()
+
+	client := kinesis.NewFromConfig(cfg)
+	for start := 0; start < len(data); start += maxPutRecords {
+		end := min(start+maxPutRecords, len(data))
+
+		entries := make([]kinesistypes.PutRecordsRequestEntry, 0, end-start)
+		for _, d := range data[start:end] {
+			entries = append(entries, kinesistypes.PutRecordsRequestEntry{
+				Data:         d,
+				PartitionKey: aws.String(partitionKey),
+			})
+		}
+
+		out, err := client.PutRecords(context.Background(), &kinesis.PutRecordsInput{
+			StreamName: aws.String(streamName),
+			Records:    entries,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to put records into stream '%s': %w", streamName, err)
+		}
+		if n := aws.ToInt32(out.FailedRecordCount); n > 0 {
+			return fmt.Errorf("failed to put %d of %d records into stream '%s'", n, len(entries), streamName)
+		}
+	}
+
+	t.Logf("Put %d records into stream %s", len(data), streamName)
+	return nil
+}
+
+// ReadKinesisRecords reads every record currently in the specified stream, starting from the oldest
+// record of each shard (TRIM_HORIZON). Records are ordered by shard, then by sequence number.
+// If the stream cannot be read, it returns an error.
+func ReadKinesisRecords(t testing.TB, cfg aws.Config, streamName string) ([]kinesistypes.Record, error) {
+	t.Helper()
+
+	ctx := context.Background()
+	client := kinesis.NewFromConfig(cfg)
+
+	// ListShards rejects the stream name once a NextToken is passed.
+	var shards []kinesistypes.Shard
+	input := &kinesis.ListShardsInput{StreamName: aws.String(streamName)}
+	for {
+		out, err := client.ListShards(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list shards of stream '%s': %w", streamName, err)
+		}
+		shards = append(shards, out.Shards...)
+		if out.NextToken == nil {
+			break
+		}
+		input = &kinesis.ListShardsInput{NextToken: out.NextToken}
+	}
+
+	var records []kinesistypes.Record
+	for _, shard := range shards {
+		it, err := client.GetShardIterator(ctx, &kinesis.GetShardIteratorInput{
+			StreamName:        aws.String(streamName),
+			ShardId:           shard.ShardId,
+			ShardIteratorType: kinesistypes.ShardIteratorTypeTrimHorizon,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get iterator for shard %s: %w", aws.ToString(shard.ShardId), err)
+		}
+
+		// Read until the shard is exhausted or the iterator has caught up with the tip.
+		iterator := it.ShardIterator
+		for iterator != nil {
+			out, err := client.GetRecords(ctx, &kinesis.GetRecordsInput{
+				ShardIterator: iterator,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get records from shard %s: %w", aws.ToString(shard.ShardId), err)
+			}
+			records = append(records, out.Records...)
+			if len(out.Records) == 0 || aws.ToInt64(out.MillisBehindLatest) == 0 {
+				break
+			}
+			iterator = out.NextShardIterator
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,186 @@
+package localstack_test
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/vvatanabe/dockertestx/localstack"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestLocalStackSQS demonstrates creating a queue, sending and receiving a message.
+func TestLocalStackSQS(t *testing.T) {
+	// Start a LocalStack container with only SQS enabled.
+	cfg, cleanup := localstack.Run(t, "sqs")
+	defer cleanup()
+
+	queueURL, err := localstack.PrepSQSQueue(t, cfg, "test-queue")
+	if err != nil {
+		t.Fatalf("PrepSQSQueue failed: %v", err)
+	}
+
+	ctx := context.Background()
+	client := sqs.NewFromConfig(cfg)
+
+	// Send a message
+	if _, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(queueURL),
+		MessageBody: aws.String("hello localstack"),
+	}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	// Receive it back
+	out, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueURL),
+		MaxNumberOfMessages: 1,
+		WaitTimeSeconds:     5,
+	})
+	if err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	if len(out.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(out.Messages))
+	}
+	if got := aws.ToString(out.Messages[0].Body); got != "hello localstack" {
+		t.Errorf("expected body 'hello localstack', got '%s'", got)
+	}
+}
+
+// TestLocalStackSNS demonstrates creating a topic with the default services.
+func TestLocalStackSNS(t *testing.T) {
+	cfg, cleanup := localstack.Run(t)
+	defer cleanup()
+
+	topicArn, err := localstack.PrepSNSTopic(t, cfg, "test-topic")
+	if err != nil {
+		t.Fatalf("PrepSNSTopic failed: %v", err)
+	}
+	if !strings.HasSuffix(topicArn, ":test-topic") {
+		t.Errorf("unexpected topic ARN: %s", topicArn)
+	}
+
+	// Publishing to the topic must succeed
+	client := sns.NewFromConfig(cfg)
+	if _, err := client.Publish(context.Background(), &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Message:  aws.String("hello sns"),
+	}); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+}
+
+// TestLocalStackSQSFIFOQueue demonstrates creating a FIFO queue.
+func TestLocalStackSQSFIFOQueue(t *testing.T) {
+	cfg, cleanup := localstack.Run(t, "sqs")
+	defer cleanup()
+
+	queueURL, err := localstack.PrepSQSFIFOQueue(t, cfg, "orders")
+	if err != nil {
+		t.Fatalf("PrepSQSFIFOQueue failed: %v", err)
+	}
+	if !strings.HasSuffix(queueURL, "/orders.fifo") {
+		t.Errorf("unexpected queue URL: %s", queueURL)
+	}
+
+	// Content-based deduplication makes a MessageDeduplicationId unnecessary
+	client := sqs.NewFromConfig(cfg)
+	if _, err := client.SendMessage(context.Background(), &sqs.SendMessageInput{
+		QueueUrl:       aws.String(queueURL),
+		MessageBody:    aws.String("order-1"),
+		MessageGroupId: aws.String("customer-1"),
+	}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+}
+
+// TestLocalStackSQSQueueWithDLQ demonstrates that a message received more than maxReceiveCount
+// times without being deleted is moved to the dead-letter queue.
+func TestLocalStackSQSQueueWithDLQ(t *testing.T) {
+	cfg, cleanup := localstack.Run(t, "sqs")
+	defer cleanup()
+
+	queueURL, dlqURL, err := localstack.PrepSQSQueueWithDLQ(t, cfg, "jobs", "jobs-dlq", 1)
+	if err != nil {
+		t.Fatalf("PrepSQSQueueWithDLQ failed: %v", err)
+	}
+
+	ctx := context.Background()
+	client := sqs.NewFromConfig(cfg)
+	if _, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(queueURL),
+		MessageBody: aws.String("poison"),
+	}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	// Receive the message without deleting it until it exceeds maxReceiveCount. Making it
+	// visible again right away avoids waiting for the visibility timeout.
+	for i := 0; i < 2; i++ {
+		out, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:        aws.String(queueURL),
+			WaitTimeSeconds: 1,
+		})
+		if err != nil {
+			t.Fatalf("failed to receive message: %v", err)
+		}
+		for _, m := range out.Messages {
+			if _, err := client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+				QueueUrl:          aws.String(queueURL),
+				ReceiptHandle:     m.ReceiptHandle,
+				VisibilityTimeout: 0,
+			}); err != nil {
+				t.Fatalf("failed to change message visibility: %v", err)
+			}
+		}
+	}
+
+	out, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:        aws.String(dlqURL),
+		WaitTimeSeconds: 5,
+	})
+	if err != nil {
+		t.Fatalf("failed to receive message from DLQ: %v", err)
+	}
+	if len(out.Messages) != 1 || aws.ToString(out.Messages[0].Body) != "poison" {
+		t.Fatalf("expected the message in the DLQ, got %+v", out.Messages)
+	}
+}
+
+// TestLocalStackKinesis demonstrates creating a stream, putting records and reading them back.
+func TestLocalStackKinesis(t *testing.T) {
+	cfg, cleanup := localstack.Run(t, "kinesis")
+	defer cleanup()
+
+	streamArn, err := localstack.PrepKinesisStream(t, cfg, "events", 1)
+	if err != nil {
+		t.Fatalf("PrepKinesisStream failed: %v", err)
+	}
+	if !strings.HasSuffix(streamArn, "/events") {
+		t.Errorf("unexpected stream ARN: %s", streamArn)
+	}
+
+	want := []string{"created", "updated", "deleted"}
+	var data [][]byte
+	for _, w := range want {
+		data = append(data, []byte(w))
+	}
+	if err := localstack.PutKinesisRecords(t, cfg, "events", "order-1", data); err != nil {
+		t.Fatalf("PutKinesisRecords failed: %v", err)
+	}
+
+	records, err := localstack.ReadKinesisRecords(t, cfg, "events")
+	if err != nil {
+		t.Fatalf("ReadKinesisRecords failed: %v", err)
+	}
+	var got []string
+	for _, r := range records {
+		got = append(got, string(r.Data))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected records %v, got %v", want, got)
+	}
+}
@@ -1,10 +1,13 @@
 package memcached
 
 import (
+	"errors"
 	"fmt"
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -14,6 +17,22 @@ const (
 	defaultMemcachedTag   = "1.6.18"
 )
 
+// WithMemcachedMemoryMB returns a RunOption that caps the memory memcached uses for items
+// to mb megabytes (the -m flag). A small cap is useful to test eviction behavior.
+func WithMemcachedMemoryMB(mb int) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Cmd = append(opts.Cmd, "-m", strconv.Itoa(mb))
+	}
+}
+
+// WithMemcachedMaxItemSize returns a RunOption that sets the maximum size of a single item
+// to bytes (the -I flag). The default memcached limit is 1MB.
+func WithMemcachedMaxItemSize(bytes int) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Cmd = append(opts.Cmd, "-I", strconv.Itoa(bytes))
+	}
+}
+
 // Run starts a Memcached Docker container using the default settings and returns a connected
 // *memcache.Client along with a cleanup function. It uses the default Memcached image ("memcached")
 // with tag "1.6.18". For more customization, use RunWithOptions.
@@ -47,6 +66,8 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		opt(defaultRunOpts)
 	}
 
+	settings := internal.TakeSettings(defaultRunOpts)
+
 	// Pass optional host configuration options
 	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
 	if err != nil {
@@ -60,11 +81,17 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 	}
 	t.Logf("memcached container is running on host port '%s'", actualPort)
 
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure memcached container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
 	// Create Memcached client
 	var client *memcache.Client
 
 	// Try to connect to Memcached with retries
-	if err = pool.Retry(func() error {
+	if err = settings.Retry(pool, func() error {
 		client = memcache.New(actualPort)
 		// Ping the server by attempting to get a non-existent key
 		// This will return ErrCacheMiss if the server is responsive
@@ -74,11 +101,13 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		}
 		return nil
 	}); err != nil {
+		logs.Dump()
 		_ = pool.Purge(resource)
 		t.Fatalf("could not connect to memcached: %s", err)
 	}
 
 	cleanup := func() {
+		logs.Stop()
 		if err := pool.Purge(resource); err != nil {
 			t.Logf("failed to remove memcached container: %s", err)
 		}
@@ -87,6 +116,98 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 	return client, cleanup
 }
 
+// RunCluster starts nodes Memcached Docker containers using the default settings and returns a
+// *memcache.Client that shards keys across all of them, along with a cleanup function that removes
+// every container. For more customization, use RunClusterWithOptions.
+func RunCluster(t testing.TB, nodes int) (*memcache.Client, func()) {
+	return RunClusterWithOptions(t, nodes, nil)
+}
+
+// RunClusterWithOptions is like RunWithOptions but starts nodes containers and returns a client
+// constructed with memcache.New(addr1, addr2, ...), so that client-side key distribution can be
+// tested. The same runOpts and hostOpts are applied to every node, and the client is only returned
+// once every node responds to a ping.
+func RunClusterWithOptions(t testing.TB, nodes int, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*memcache.Client, func()) {
+	t.Helper()
+
+	if nodes <= 0 {
+		t.Fatalf("memcached cluster needs at least one node, got %d", nodes)
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	var resources []*dockertest.Resource
+	var logs []*internal.LogCapture
+	purgeAll := func() {
+		for _, l := range logs {
+			l.Stop()
+		}
+		for _, resource := range resources {
+			if err := pool.Purge(resource); err != nil {
+				t.Logf("failed to remove memcached container: %s", err)
+			}
+		}
+	}
+	dumpAll := func() {
+		for _, l := range logs {
+			l.Dump()
+		}
+	}
+
+	// Every node is started with the same options, so they share the settings.
+	var settings *internal.Settings
+	addrs := make([]string, 0, nodes)
+	for i := 0; i < nodes; i++ {
+		// Set default run options for Memcached
+		defaultRunOpts := &dockertest.RunOptions{
+			Repository: defaultMemcachedImage,
+			Tag:        defaultMemcachedTag,
+		}
+
+		// Apply any provided RunOption functions to override defaults
+		for _, opt := range runOpts {
+			opt(defaultRunOpts)
+		}
+
+		settings = internal.TakeSettings(defaultRunOpts)
+
+		// Pass optional host configuration options
+		resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+		if err != nil {
+			purgeAll()
+			t.Fatalf("failed to start memcached container %d: %s", i, err)
+		}
+		resources = append(resources, resource)
+		if err := settings.OnStart(pool, resource); err != nil {
+			purgeAll()
+			t.Fatalf("failed to configure memcached container %d: %s", i, err)
+		}
+		logs = append(logs, internal.CaptureLogs(t.Logf, pool, resource, settings))
+
+		actualPort := resource.GetHostPort("11211/tcp")
+		if actualPort == "" {
+			purgeAll()
+			t.Fatalf("no host port was assigned for memcached container %d", i)
+		}
+		addrs = append(addrs, actualPort)
+	}
+	t.Logf("memcached cluster is running on host ports %v", addrs)
+
+	client := memcache.New(addrs...)
+
+	// Ping checks every server in the client's server list
+	if err = settings.Retry(pool, client.Ping); err != nil {
+		dumpAll()
+		purgeAll()
+		t.Fatalf("could not connect to memcached cluster: %s", err)
+	}
+
+	return client, purgeAll
+}
+
 // PrepMemcached sets up test data in a Memcached instance.
 // It accepts a list of memcache.Item pointers and stores them in the cache.
 // If any operation fails, it returns an error.
@@ -104,3 +225,45 @@ func PrepMemcached(t testing.TB, client *memcache.Client, items ...*memcache.Ite
 	}
 	return nil
 }
+
+// GetMemcachedMulti fetches multiple keys from a Memcached instance in a single round trip
+// per server. The returned map only contains keys that were found; missing keys are not an error.
+// If the request fails, it returns an error.
+func GetMemcachedMulti(t testing.TB, client *memcache.Client, keys []string) (map[string]*memcache.Item, error) {
+	t.Helper()
+
+	items, err := client.GetMulti(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %d keys: %w", len(keys), err)
+	}
+	return items, nil
+}
+
+// maxCASAttempts bounds how many times CompareAndSwapMemcached retries after a CAS conflict.
+const maxCASAttempts = 50
+
+// CompareAndSwapMemcached atomically updates the value stored at key using the optimistic
+// Gets/CompareAndSwap flow: it fetches the item (and its CAS ID), computes the new value with
+// update, and stores it only if nobody modified the item in between. On memcache.ErrCASConflict
+// the whole cycle is retried, up to 50 attempts.
+// The key must already exist. If any operation fails, it returns an error.
+func CompareAndSwapMemcached(t testing.TB, client *memcache.Client, key string, update func(old []byte) []byte) error {
+	t.Helper()
+
+	for attempt := 1; attempt <= maxCASAttempts; attempt++ {
+		item, err := client.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to get item with key '%s': %w", key, err)
+		}
+
+		item.Value = update(item.Value)
+		err = client.CompareAndSwap(item)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, memcache.ErrCASConflict) {
+			return fmt.Errorf("failed to compare-and-swap item with key '%s': %w", key, err)
+		}
+	}
+	return fmt.Errorf("failed to compare-and-swap item with key '%s' after %d attempts: %w", key, maxCASAttempts, memcache.ErrCASConflict)
+}
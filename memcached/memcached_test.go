@@ -1,10 +1,13 @@
 package memcached_test
 
 import (
+	"fmt"
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/vvatanabe/dockertestx/memcached"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -180,3 +183,135 @@ func TestMemcachedOperations(t *testing.T) {
 		}
 	})
 }
+
+// TestGetMemcachedMulti demonstrates fetching several keys at once.
+func TestGetMemcachedMulti(t *testing.T) {
+	client, cleanup := memcached.Run(t)
+	defer cleanup()
+
+	// Seed three items
+	items := []*memcache.Item{
+		{Key: "multi-1", Value: []byte("value-1")},
+		{Key: "multi-2", Value: []byte("value-2")},
+		{Key: "multi-3", Value: []byte("value-3")},
+	}
+	if err := memcached.PrepMemcached(t, client, items...); err != nil {
+		t.Fatalf("PrepMemcached failed: %v", err)
+	}
+
+	// Fetch them all in one call, including a key that does not exist
+	got, err := memcached.GetMemcachedMulti(t, client, []string{"multi-1", "multi-2", "multi-3", "missing"})
+	if err != nil {
+		t.Fatalf("GetMemcachedMulti failed: %v", err)
+	}
+
+	if len(got) != len(items) {
+		t.Errorf("expected %d items, got %d", len(items), len(got))
+	}
+	for _, item := range items {
+		fetched, ok := got[item.Key]
+		if !ok {
+			t.Errorf("expected key '%s' to be returned", item.Key)
+			continue
+		}
+		if string(fetched.Value) != string(item.Value) {
+			t.Errorf("expected value '%s' for key '%s', got '%s'", item.Value, item.Key, fetched.Value)
+		}
+	}
+}
+
+// TestMemcachedCluster demonstrates sharding keys across several Memcached nodes.
+func TestMemcachedCluster(t *testing.T) {
+	client, cleanup := memcached.RunCluster(t, 3)
+	defer cleanup()
+
+	// Write many keys so that every node receives some
+	const keyCount = 100
+	items := make([]*memcache.Item, 0, keyCount)
+	keys := make([]string, 0, keyCount)
+	for i := 0; i < keyCount; i++ {
+		key := fmt.Sprintf("cluster-key-%d", i)
+		keys = append(keys, key)
+		items = append(items, &memcache.Item{Key: key, Value: []byte(key)})
+	}
+	if err := memcached.PrepMemcached(t, client, items...); err != nil {
+		t.Fatalf("PrepMemcached failed: %v", err)
+	}
+
+	// Every key must be retrievable through the sharding client
+	got, err := memcached.GetMemcachedMulti(t, client, keys)
+	if err != nil {
+		t.Fatalf("GetMemcachedMulti failed: %v", err)
+	}
+	if len(got) != keyCount {
+		t.Errorf("expected %d keys, got %d", keyCount, len(got))
+	}
+}
+
+// TestCompareAndSwapMemcached demonstrates concurrent updates without lost writes.
+func TestCompareAndSwapMemcached(t *testing.T) {
+	client, cleanup := memcached.Run(t)
+	defer cleanup()
+
+	key := "counter"
+	if err := memcached.PrepMemcached(t, client, &memcache.Item{Key: key, Value: []byte("0")}); err != nil {
+		t.Fatalf("PrepMemcached failed: %v", err)
+	}
+
+	increment := func(old []byte) []byte {
+		n, _ := strconv.Atoi(string(old))
+		return []byte(strconv.Itoa(n + 1))
+	}
+
+	// Two goroutines increment the same counter concurrently
+	const workers, increments = 2, 25
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < increments; i++ {
+				if err := memcached.CompareAndSwapMemcached(t, client, key, increment); err != nil {
+					t.Errorf("CompareAndSwapMemcached failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := client.Get(key)
+	if err != nil {
+		t.Fatalf("failed to get counter: %v", err)
+	}
+	if want := strconv.Itoa(workers * increments); string(got.Value) != want {
+		t.Errorf("expected counter %s, got %s", want, got.Value)
+	}
+}
+
+// TestMemcachedEviction demonstrates running memcached with a small memory cap.
+func TestMemcachedEviction(t *testing.T) {
+	client, cleanup := memcached.RunWithOptions(t, []func(*dockertest.RunOptions){
+		memcached.WithMemcachedMemoryMB(1),
+		memcached.WithMemcachedMaxItemSize(512 * 1024),
+	})
+	defer cleanup()
+
+	// Write far more data than fits into 1MB
+	value := make([]byte, 50*1024)
+	const itemCount = 100
+	for i := 0; i < itemCount; i++ {
+		item := &memcache.Item{Key: fmt.Sprintf("evict-%d", i), Value: value}
+		if err := memcached.PrepMemcached(t, client, item); err != nil {
+			t.Fatalf("PrepMemcached failed: %v", err)
+		}
+	}
+
+	// The oldest key must have been evicted, the newest one must still be present
+	if _, err := client.Get("evict-0"); err != memcache.ErrCacheMiss {
+		t.Errorf("expected the oldest key to be evicted, got: %v", err)
+	}
+	if _, err := client.Get(fmt.Sprintf("evict-%d", itemCount-1)); err != nil {
+		t.Errorf("expected the newest key to be present, got: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+package minio
+
+var EndpointURL = endpointURL
+
+var CopySource = copySource
+
+var ResourceURL = resourceURL
@@ -3,14 +3,24 @@ package minio
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/vvatanabe/dockertestx/internal"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
 	"strings"
 	"testing"
 	"time"
@@ -21,8 +31,60 @@ const (
 	defaultMinIOTag   = "latest"
 	defaultAccessKey  = "minioadmin"
 	defaultSecretKey  = "minioadmin"
+	defaultRegion     = "us-east-1"
+
+	// defaultReadyTimeout bounds the total time spent waiting for MinIO to become ready.
+	defaultReadyTimeout = 30 * time.Second
+	// readyAttemptTimeout bounds a single readiness probe.
+	readyAttemptTimeout = 5 * time.Second
 )
 
+// WithReadyTimeout returns a RunOption that sets how long RunWithOptions waits for MinIO to
+// become ready before failing the test. The default is 30 seconds, which leaves room for a
+// cold image on CI.
+func WithReadyTimeout(timeout time.Duration) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		internal.SettingsOf(opts).ReadyTimeout = timeout
+	}
+}
+
+// WithRegion returns a RunOption that sets the region of the MinIO server (MINIO_SITE_REGION).
+// The returned S3 client is configured with the same region, which is used for SigV4 signing
+// and as the LocationConstraint when PrepBucket creates buckets. The default is "us-east-1".
+func WithRegion(region string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Env = append(opts.Env, "MINIO_SITE_REGION="+region)
+	}
+}
+
+// KMSKeyName is the name of the static key that WithKMS configures. It can be passed to
+// WithSSEKMS as the key ID.
+const KMSKeyName = "dockertestx-key"
+
+// WithKMS returns a RunOption that configures MinIO with a static KMS key (MINIO_KMS_SECRET_KEY)
+// named KMSKeyName. MinIO needs a KMS to store objects with server-side encryption, so uploads
+// with WithServerSideEncryption or WithSSEKMS fail without it. The key is fixed and for tests only.
+func WithKMS() func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Env = append(opts.Env, "MINIO_KMS_SECRET_KEY="+KMSKeyName+":ZG9ja2VydGVzdHgtc3RhdGljLXRlc3Qta21zLWtleSE=")
+	}
+}
+
+// Endpoint describes how to reach a MinIO container started by RunWithEndpoint.
+type Endpoint struct {
+	// URL is the S3 API endpoint (9000/tcp), e.g. "http://localhost:55001".
+	URL string
+	// ConsoleURL is the MinIO web console (9001/tcp). Printing it from a failing test
+	// (e.g. with t.Logf) lets you inspect buckets and objects interactively.
+	ConsoleURL string
+	// AccessKey is the root user (MINIO_ROOT_USER).
+	AccessKey string
+	// SecretKey is the root password (MINIO_ROOT_PASSWORD).
+	SecretKey string
+	// Region is the region the S3 client is configured with.
+	Region string
+}
+
 // Run starts a MinIO Docker container using the default settings and returns a configured S3 client
 // along with a cleanup function. It uses the default MinIO image ("minio/minio") with tag "latest".
 // For more customization, use RunWithOptions.
@@ -35,13 +97,24 @@ func Run(t testing.TB) (*s3.Client, func()) {
 //   - Repository: "minio/minio"
 //   - Tag: "latest"
 //   - Environment: MINIO_ROOT_USER=minioadmin, MINIO_ROOT_PASSWORD=minioadmin
-//   - Command: ["server", "/data"]
+//   - Command: ["server", "/data", "--console-address", ":9001"]
+//   - Exposed ports: 9000/tcp (S3 API), 9001/tcp (console)
+//   - Readiness timeout: 30 seconds (see WithReadyTimeout)
 //
 // Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
 // and optional host configuration functions can be provided via hostOpts.
 func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*s3.Client, func()) {
 	t.Helper()
 
+	client, _, cleanup := RunWithEndpoint(t, runOpts, hostOpts...)
+	return client, cleanup
+}
+
+// RunWithEndpoint behaves like RunWithOptions but additionally returns the Endpoint of the
+// started container, including the console URL and the root credentials.
+func RunWithEndpoint(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*s3.Client, Endpoint, func()) {
+	t.Helper()
+
 	// Set default run options for MinIO
 	defaultRunOpts := &dockertest.RunOptions{
 		Repository: defaultMinIOImage,
@@ -50,13 +123,19 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 			"MINIO_ROOT_USER=" + defaultAccessKey,
 			"MINIO_ROOT_PASSWORD=" + defaultSecretKey,
 		},
-		Cmd: []string{"server", "/data"},
+		Cmd:          []string{"server", "/data", "--console-address", ":9001"},
+		ExposedPorts: []string{"9000/tcp", "9001/tcp"},
 	}
 
 	// Apply any provided RunOption functions to override defaults
 	for _, opt := range runOpts {
 		opt(defaultRunOpts)
 	}
+	settings := internal.TakeSettings(defaultRunOpts)
+	readyTimeout := defaultReadyTimeout
+	if settings.ReadyTimeout > 0 {
+		readyTimeout = settings.ReadyTimeout
+	}
 
 	// Start the container
 	pool, err := dockertest.NewPool("")
@@ -70,35 +149,53 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		t.Fatalf("failed to start MinIO container: %s", err)
 	}
 
-	// Get the port that MinIO is running on
-	actualPort := resource.GetHostPort("9000/tcp")
-	if actualPort == "" {
+	// Get the address that MinIO is running on
+	apiURL, err := resourceURL(pool, resource, "9000/tcp")
+	if err != nil {
 		_ = pool.Purge(resource)
-		t.Fatalf("no host port was assigned for the MinIO container")
+		t.Fatalf("failed to get the address of the MinIO container: %s", err)
 	}
 
-	t.Logf("MinIO container is running on host port '%s'", actualPort)
-	// GetHostPort may return a format like "localhost:55250",
-	// so remove the "localhost:" prefix if present
-	actualPort = strings.TrimPrefix(actualPort, "localhost:")
+	t.Logf("MinIO container is running on '%s'", apiURL)
 
-	// Get access and secret keys from environment variables
-	accessKey := internal.GetEnvValue(defaultRunOpts.Env, "MINIO_ROOT_USER")
-	secretKey := internal.GetEnvValue(defaultRunOpts.Env, "MINIO_ROOT_PASSWORD")
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure MinIO container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	// Get access and secret keys from environment variables. When they are not set, MinIO
+	// uses its built-in credentials, which are the same as the defaults.
+	accessKey := internal.GetEnvValueOr(defaultRunOpts.Env, "MINIO_ROOT_USER", defaultAccessKey)
+	secretKey := internal.GetEnvValueOr(defaultRunOpts.Env, "MINIO_ROOT_PASSWORD", defaultSecretKey)
+	region := internal.GetEnvValue(defaultRunOpts.Env, "MINIO_SITE_REGION")
+	if region == "" {
+		region = defaultRegion
+	}
 
-	// Wait for MinIO to be ready
+	// Wait for MinIO to be ready; the pool bounds the whole retry loop
+	// while each attempt gets its own, shorter timeout.
 	var s3Client *s3.Client
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second) // Set timeout to 3 seconds
-	defer cancel()
+	pool.MaxWait = readyTimeout
 
-	// Build the endpoint URL with the correct localhost:port format
-	endpoint := fmt.Sprintf("http://localhost:%s", actualPort)
-	t.Logf("Connecting to MinIO endpoint: %s with credentials %s:%s", endpoint, accessKey, secretKey)
+	endpoint := Endpoint{
+		URL:       apiURL,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Region:    region,
+	}
+	if consoleURL, err := resourceURL(pool, resource, "9001/tcp"); err == nil {
+		endpoint.ConsoleURL = consoleURL
+	}
+	t.Logf("Connecting to MinIO endpoint: %s with credentials %s:%s", endpoint.URL, accessKey, secretKey)
+
+	if err = settings.Retry(pool, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), readyAttemptTimeout)
+		defer cancel()
 
-	if err = pool.Retry(func() error {
 		// Load AWS SDK Go v2 configuration
 		cfg, err := config.LoadDefaultConfig(ctx,
-			config.WithRegion("us-east-1"),
+			config.WithRegion(region),
 			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
 		)
 		if err != nil {
@@ -108,7 +205,7 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		// Create S3 client with direct option settings
 		s3Client = s3.NewFromConfig(cfg, func(o *s3.Options) {
 			o.UsePathStyle = true // MinIO requires path-style addressing
-			o.BaseEndpoint = aws.String(endpoint)
+			o.BaseEndpoint = aws.String(endpoint.URL)
 		})
 
 		// Test if the S3 API is responding
@@ -119,20 +216,39 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		}
 		return nil
 	}); err != nil {
+		logs.Dump()
 		_ = pool.Purge(resource)
 		t.Fatalf("failed to connect to MinIO: %s", err)
 	}
 
 	cleanup := func() {
+		logs.Stop()
 		if err := pool.Purge(resource); err != nil {
 			t.Logf("failed to remove MinIO container: %s", err)
 		}
 	}
 
-	return s3Client, cleanup
+	return s3Client, endpoint, cleanup
+}
+
+// resourceURL returns the HTTP URL under which containerPort of resource is reachable from the
+// test, on the host of a remote Docker daemon if DOCKER_HOST points at one.
+func resourceURL(pool *dockertest.Pool, resource *dockertest.Resource, containerPort string) (string, error) {
+	host, port, err := internal.HostAddress(pool, resource, containerPort)
+	if err != nil {
+		return "", err
+	}
+	return endpointURL(host, port), nil
+}
+
+// endpointURL builds an HTTP URL from the host and port returned by internal.HostAddress.
+func endpointURL(host, port string) string {
+	return "http://" + net.JoinHostPort(host, port)
 }
 
-// PrepBucket creates a bucket if it doesn't exist
+// PrepBucket creates a bucket if it doesn't exist.
+// The bucket is created in the client's region; S3 requires the LocationConstraint to be
+// omitted for "us-east-1", so it is only sent for other regions.
 func PrepBucket(t testing.TB, client *s3.Client, bucketName string) error {
 	t.Helper()
 	ctx := context.Background()
@@ -144,9 +260,15 @@ func PrepBucket(t testing.TB, client *s3.Client, bucketName string) error {
 
 	if err != nil {
 		// Create bucket if it doesn't exist
-		_, err = client.CreateBucket(ctx, &s3.CreateBucketInput{
+		input := &s3.CreateBucketInput{
 			Bucket: aws.String(bucketName),
-		})
+		}
+		if region := client.Options().Region; region != "" && region != defaultRegion {
+			input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+				LocationConstraint: types.BucketLocationConstraint(region),
+			}
+		}
+		_, err = client.CreateBucket(ctx, input)
 		if err != nil {
 			return fmt.Errorf("failed to create bucket %s: %w", bucketName, err)
 		}
@@ -155,16 +277,42 @@ func PrepBucket(t testing.TB, client *s3.Client, bucketName string) error {
 	return nil
 }
 
+// UploadOption configures the PutObject request sent by UploadObject.
+type UploadOption func(*s3.PutObjectInput)
+
+// WithServerSideEncryption returns an UploadOption that stores the object with the given
+// server-side encryption, e.g. types.ServerSideEncryptionAes256 for SSE-S3.
+// The MinIO container must be started with WithKMS.
+func WithServerSideEncryption(sse types.ServerSideEncryption) UploadOption {
+	return func(in *s3.PutObjectInput) {
+		in.ServerSideEncryption = sse
+	}
+}
+
+// WithSSEKMS returns an UploadOption that stores the object with SSE-KMS using the given key ID,
+// e.g. KMSKeyName. The MinIO container must be started with WithKMS.
+func WithSSEKMS(keyID string) UploadOption {
+	return func(in *s3.PutObjectInput) {
+		in.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		in.SSEKMSKeyId = aws.String(keyID)
+	}
+}
+
 // UploadObject uploads an object to a bucket
-func UploadObject(t testing.TB, client *s3.Client, bucketName, key string, body []byte) error {
+func UploadObject(t testing.TB, client *s3.Client, bucketName, key string, body []byte, opts ...UploadOption) error {
 	t.Helper()
 	ctx := context.Background()
 
-	_, err := client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(key),
 		Body:   bytes.NewReader(body),
-	})
+	}
+	for _, opt := range opts {
+		opt(input)
+	}
+
+	_, err := client.PutObject(ctx, input)
 
 	if err != nil {
 		return fmt.Errorf("failed to upload object %s to bucket %s: %w", key, bucketName, err)
@@ -173,6 +321,133 @@ func UploadObject(t testing.TB, client *s3.Client, bucketName, key string, body
 	return nil
 }
 
+// GetObjectEncryption returns the server-side encryption an object is stored with, as reported by
+// HeadObject, e.g. types.ServerSideEncryptionAes256 for SSE-S3. It is empty for unencrypted objects.
+func GetObjectEncryption(t testing.TB, client *s3.Client, bucketName, key string) (types.ServerSideEncryption, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	resp, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to head object %s in bucket %s: %w", key, bucketName, err)
+	}
+
+	return resp.ServerSideEncryption, nil
+}
+
+// minPartSize is the smallest part S3 accepts in a multipart upload, except for the last part.
+const minPartSize = 5 << 20
+
+// UploadMultipart uploads body as an object with a multipart upload, splitting it into parts of
+// partSize bytes (the last part may be smaller). partSize must be at least 5 MiB, the minimum S3
+// and MinIO accept. If any part fails, the upload is aborted so that no parts are left behind,
+// and an error is returned.
+func UploadMultipart(t testing.TB, client *s3.Client, bucketName, key string, body []byte, partSize int64) error {
+	t.Helper()
+	ctx := context.Background()
+
+	if partSize < minPartSize {
+		return fmt.Errorf("part size %d is smaller than the minimum of %d bytes", partSize, minPartSize)
+	}
+
+	created, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create multipart upload for object %s in bucket %s: %w", key, bucketName, err)
+	}
+	uploadID := created.UploadId
+
+	abort := func(err error) error {
+		if _, abortErr := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucketName),
+			Key:      aws.String(key),
+			UploadId: uploadID,
+		}); abortErr != nil {
+			t.Logf("failed to abort multipart upload for object %s: %s", key, abortErr)
+		}
+		return err
+	}
+
+	var parts []types.CompletedPart
+	for start := int64(0); start < int64(len(body)) || len(parts) == 0; start += partSize {
+		end := min(start+partSize, int64(len(body)))
+		partNumber := int32(len(parts) + 1)
+
+		out, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(bucketName),
+			Key:        aws.String(key),
+			UploadId:   uploadID,
+			PartNumber: aws.Int32(partNumber),
+			Body:       bytes.NewReader(body[start:end]),
+		})
+		if err != nil {
+			return abort(fmt.Errorf("failed to upload part %d of object %s: %w", partNumber, key, err))
+		}
+		parts = append(parts, types.CompletedPart{
+			ETag:       out.ETag,
+			PartNumber: aws.Int32(partNumber),
+		})
+	}
+
+	if _, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	}); err != nil {
+		return abort(fmt.Errorf("failed to complete multipart upload of object %s: %w", key, err))
+	}
+
+	t.Logf("Uploaded object %s to bucket %s in %d parts", key, bucketName, len(parts))
+	return nil
+}
+
+// CopyObject copies the object srcKey in srcBucket to dstKey in dstBucket on the server side, without
+// downloading it. The buckets may be the same; dstBucket must already exist.
+// If the copy fails, it returns an error.
+func CopyObject(t testing.TB, client *s3.Client, srcBucket, srcKey, dstBucket, dstKey string) error {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(dstBucket),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(copySource(srcBucket, srcKey)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy object %s/%s to %s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+	}
+
+	return nil
+}
+
+// NewPresignClient returns an *s3.PresignClient for client, with which any S3 operation can be
+// presigned, e.g. PresignGetObject or PresignPutObject. It keeps the endpoint of client, which for a
+// client returned by Run is the port MinIO is mapped to on the host, and forces path-style
+// addressing, so the presigned URLs can be fetched with a plain http.Client from the test.
+func NewPresignClient(client *s3.Client) *s3.PresignClient {
+	return s3.NewPresignClient(client, func(o *s3.PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, func(o *s3.Options) {
+			o.UsePathStyle = true // virtual-hosted bucket names do not resolve to the mapped port
+		})
+	})
+}
+
+// copySource builds the "bucket/key" CopySource of a CopyObject request. Each segment of the key is
+// URL-encoded, while the slashes between them are kept.
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
+
 // PrepS3Objects prepares a bucket with the given objects
 func PrepS3Objects(t testing.TB, client *s3.Client, bucketName string, objects map[string][]byte) error {
 	t.Helper()
@@ -191,3 +466,327 @@ func PrepS3Objects(t testing.TB, client *s3.Client, bucketName string, objects m
 
 	return nil
 }
+
+// DownloadObject downloads an object from a bucket and returns its content.
+// The response body is always closed, even if reading it fails.
+func DownloadObject(t testing.TB, client *s3.Client, bucketName, key string) ([]byte, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	resp, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s from bucket %s: %w", key, bucketName, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object %s from bucket %s: %w", key, bucketName, err)
+	}
+
+	return data, nil
+}
+
+// DownloadObjects downloads several objects from a bucket and returns their contents keyed by object key
+func DownloadObjects(t testing.TB, client *s3.Client, bucketName string, keys []string) (map[string][]byte, error) {
+	t.Helper()
+
+	objects := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		data, err := DownloadObject(t, client, bucketName, key)
+		if err != nil {
+			return nil, err
+		}
+		objects[key] = data
+	}
+
+	return objects, nil
+}
+
+// ListObjects returns the keys of all objects in a bucket that start with prefix,
+// following ListObjectsV2 pagination. An empty prefix lists every object.
+func ListObjects(t testing.TB, client *s3.Client, bucketName, prefix string) ([]string, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in bucket %s: %w", bucketName, err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+
+	return keys, nil
+}
+
+// EnableVersioning turns on versioning for a bucket. Once enabled, every overwrite of a key
+// creates a new object version instead of replacing the previous content; use
+// ListObjectVersions to inspect them.
+func EnableVersioning(t testing.TB, client *s3.Client, bucketName string) error {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucketName),
+		VersioningConfiguration: &types.VersioningConfiguration{
+			Status: types.BucketVersioningStatusEnabled,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable versioning on bucket %s: %w", bucketName, err)
+	}
+
+	return nil
+}
+
+// ListObjectVersions returns all versions of the objects in a bucket whose keys start with prefix,
+// following pagination. Versions of a key are returned newest first.
+func ListObjectVersions(t testing.TB, client *s3.Client, bucketName, prefix string) ([]types.ObjectVersion, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	var versions []types.ObjectVersion
+	for {
+		page, err := client.ListObjectVersions(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list object versions in bucket %s: %w", bucketName, err)
+		}
+		versions = append(versions, page.Versions...)
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+		input.KeyMarker = page.NextKeyMarker
+		input.VersionIdMarker = page.NextVersionIdMarker
+	}
+
+	return versions, nil
+}
+
+// UploadDirectory uploads every file below localDir to a bucket. Each object key is the file's
+// path relative to localDir using forward slashes (e.g. "images/logo.png"); empty directories
+// are skipped.
+func UploadDirectory(t testing.TB, client *s3.Client, bucketName, localDir string) error {
+	t.Helper()
+
+	return UploadFS(t, client, bucketName, os.DirFS(localDir), ".")
+}
+
+// UploadFS is like UploadDirectory but reads the files below root from fsys, which makes it
+// possible to seed a bucket from an embed.FS. Object keys are relative to root.
+func UploadFS(t testing.TB, client *s3.Client, bucketName string, fsys fs.FS, root string) error {
+	t.Helper()
+
+	return fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk %s: %w", p, err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", p, err)
+		}
+
+		key := p
+		if root != "." {
+			key = strings.TrimPrefix(p, path.Clean(root)+"/")
+		}
+		return UploadObject(t, client, bucketName, key, data)
+	})
+}
+
+// SetObjectTags replaces the tag set of an object with the given tags
+func SetObjectTags(t testing.TB, client *s3.Client, bucketName, key string, tags map[string]string) error {
+	t.Helper()
+	ctx := context.Background()
+
+	tagSet := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	_, err := client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(key),
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set tags on object %s in bucket %s: %w", key, bucketName, err)
+	}
+
+	return nil
+}
+
+// GetObjectTags returns the tags of an object as a map
+func GetObjectTags(t testing.TB, client *s3.Client, bucketName, key string) (map[string]string, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	resp, err := client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags of object %s in bucket %s: %w", key, bucketName, err)
+	}
+
+	tags := make(map[string]string, len(resp.TagSet))
+	for _, tag := range resp.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return tags, nil
+}
+
+// Event is a bucket notification delivered by EnableBucketNotifications.
+type Event struct {
+	// Name is the S3 event type, e.g. "s3:ObjectCreated:Put" or "s3:ObjectRemoved:Delete".
+	Name string
+	// Bucket is the name of the bucket the event occurred in.
+	Bucket string
+	// Key is the key of the object the event refers to.
+	Key string
+	// Size is the size of the object in bytes; it is zero for removals.
+	Size int64
+	// ETag is the entity tag of the object; it is empty for removals.
+	ETag string
+}
+
+// notification is the JSON document MinIO streams for each batch of bucket events.
+type notification struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"eTag"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// emptyPayloadHash is the SHA-256 of an empty request body, used to sign the listen request.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// EnableBucketNotifications subscribes to the object created and object removed events of a bucket
+// and returns a channel that receives them, along with a cleanup function that ends the subscription
+// and closes the channel. It uses MinIO's listen API, so no notification target has to be configured
+// on the server. Events that happen after the function returns are delivered; the channel buffers
+// up to 100 events that have not been received yet. The test fails immediately if the subscription
+// cannot be established.
+func EnableBucketNotifications(t testing.TB, client *s3.Client, bucketName string) (<-chan Event, func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := listenBucket(ctx, client, bucketName)
+	if err != nil {
+		cancel()
+		t.Fatalf("failed to listen for notifications of bucket %s: %s", bucketName, err)
+	}
+
+	events := make(chan Event, 100)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(events)
+		// MinIO separates the documents with whitespace keep-alives, which the decoder skips.
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var n notification
+			if err := dec.Decode(&n); err != nil {
+				return
+			}
+			for _, r := range n.Records {
+				key, err := url.QueryUnescape(r.S3.Object.Key)
+				if err != nil {
+					key = r.S3.Object.Key
+				}
+				select {
+				case events <- Event{
+					Name:   r.EventName,
+					Bucket: r.S3.Bucket.Name,
+					Key:    key,
+					Size:   r.S3.Object.Size,
+					ETag:   r.S3.Object.ETag,
+				}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	cleanup := func() {
+		cancel()
+		_ = resp.Body.Close()
+		<-done
+	}
+
+	return events, cleanup
+}
+
+// listenBucket sends a signed ListenBucketNotification request to the endpoint of client and returns
+// the streaming response once MinIO has registered the listener.
+func listenBucket(ctx context.Context, client *s3.Client, bucketName string) (*http.Response, error) {
+	opts := client.Options()
+
+	creds, err := opts.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+
+	query := url.Values{
+		"events": []string{"s3:ObjectCreated:*", "s3:ObjectRemoved:*"},
+		// A keep-alive every second makes MinIO send the response headers right away.
+		"ping": []string{"1"},
+	}
+	target := fmt.Sprintf("%s/%s?%s", strings.TrimSuffix(aws.ToString(opts.BaseEndpoint), "/"), url.PathEscape(bucketName), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, emptyPayloadHash, "s3", opts.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return resp, nil
+}
@@ -1,14 +1,24 @@
 package minio_test
 
 import (
+	"bytes"
 	"context"
+	"embed"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/vvatanabe/dockertestx/minio"
 	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMinIO(t *testing.T) {
@@ -115,3 +125,579 @@ func TestMinIOWithOptions(t *testing.T) {
 		t.Fatalf("Failed to find bucket '%s': %v", bucketName, err)
 	}
 }
+
+func TestMinIOSlowStart(t *testing.T) {
+	// Delay the MinIO server start to simulate a slow, cold container
+	slowStart := func(o *dockertest.RunOptions) {
+		o.Entrypoint = []string{"sh", "-c", "sleep 5 && exec minio server /data"}
+		o.Cmd = nil
+	}
+
+	// Start MinIO with a readiness timeout that covers the delay
+	client, cleanup := minio.RunWithOptions(t, []func(*dockertest.RunOptions){
+		slowStart,
+		minio.WithReadyTimeout(time.Minute),
+	})
+	defer cleanup()
+
+	// Verify that the client works once Run returns
+	ctx := context.Background()
+	if _, err := client.ListBuckets(ctx, &s3.ListBucketsInput{}); err != nil {
+		t.Fatalf("Failed to list buckets: %v", err)
+	}
+}
+
+func TestMinIODownloadObject(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	bucketName := "download-test"
+
+	// Binary blob containing every byte value
+	blob := make([]byte, 256)
+	for i := range blob {
+		blob[i] = byte(i)
+	}
+	objects := map[string][]byte{
+		"blob.bin": blob,
+		"text.txt": []byte("Hello, MinIO!"),
+	}
+	if err := minio.PrepS3Objects(t, client, bucketName, objects); err != nil {
+		t.Fatalf("PrepS3Objects failed: %v", err)
+	}
+
+	// Download a single object
+	got, err := minio.DownloadObject(t, client, bucketName, "blob.bin")
+	if err != nil {
+		t.Fatalf("DownloadObject failed: %v", err)
+	}
+	if !bytes.Equal(got, blob) {
+		t.Errorf("Downloaded blob does not match the uploaded content")
+	}
+
+	// Download several objects at once
+	all, err := minio.DownloadObjects(t, client, bucketName, []string{"blob.bin", "text.txt"})
+	if err != nil {
+		t.Fatalf("DownloadObjects failed: %v", err)
+	}
+	for key, want := range objects {
+		if !bytes.Equal(all[key], want) {
+			t.Errorf("Object '%s' content mismatch", key)
+		}
+	}
+}
+
+func TestMinIOListObjects(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	bucketName := "list-test"
+	objects := map[string][]byte{
+		"logs/2024/a.log":  []byte("a"),
+		"logs/2024/b.log":  []byte("b"),
+		"logs/2025/c.log":  []byte("c"),
+		"images/photo.png": []byte("png"),
+	}
+	if err := minio.PrepS3Objects(t, client, bucketName, objects); err != nil {
+		t.Fatalf("PrepS3Objects failed: %v", err)
+	}
+
+	// Only keys under the prefix are returned
+	keys, err := minio.ListObjects(t, client, bucketName, "logs/2024/")
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+	want := []string{"logs/2024/a.log", "logs/2024/b.log"}
+	slices.Sort(keys)
+	if !slices.Equal(keys, want) {
+		t.Errorf("Expected keys %v, got %v", want, keys)
+	}
+
+	// An empty prefix lists everything
+	all, err := minio.ListObjects(t, client, bucketName, "")
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+	if len(all) != len(objects) {
+		t.Errorf("Expected %d keys, got %d", len(objects), len(all))
+	}
+}
+
+func TestMinIOWithRegion(t *testing.T) {
+	// Start MinIO in a region other than us-east-1
+	region := "eu-west-1"
+	client, cleanup := minio.RunWithOptions(t, []func(*dockertest.RunOptions){
+		minio.WithRegion(region),
+	})
+	defer cleanup()
+
+	if got := client.Options().Region; got != region {
+		t.Errorf("Expected client region '%s', got '%s'", region, got)
+	}
+
+	// Bucket creation sends the matching LocationConstraint
+	bucketName := "regional-bucket"
+	if err := minio.PrepBucket(t, client, bucketName); err != nil {
+		t.Fatalf("PrepBucket failed: %v", err)
+	}
+
+	ctx := context.Background()
+	resp, err := client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		t.Fatalf("Failed to get bucket location: %v", err)
+	}
+	if string(resp.LocationConstraint) != region {
+		t.Errorf("Expected bucket location '%s', got '%s'", region, resp.LocationConstraint)
+	}
+}
+
+func TestMinIOVersioning(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	bucketName := "versioned-bucket"
+	if err := minio.PrepBucket(t, client, bucketName); err != nil {
+		t.Fatalf("PrepBucket failed: %v", err)
+	}
+	if err := minio.EnableVersioning(t, client, bucketName); err != nil {
+		t.Fatalf("EnableVersioning failed: %v", err)
+	}
+
+	// Upload the same key twice
+	key := "config.json"
+	for _, body := range []string{`{"v":1}`, `{"v":2}`} {
+		if err := minio.UploadObject(t, client, bucketName, key, []byte(body)); err != nil {
+			t.Fatalf("UploadObject failed: %v", err)
+		}
+	}
+
+	versions, err := minio.ListObjectVersions(t, client, bucketName, key)
+	if err != nil {
+		t.Fatalf("ListObjectVersions failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got %d", len(versions))
+	}
+	if aws.ToString(versions[0].VersionId) == aws.ToString(versions[1].VersionId) {
+		t.Errorf("Expected distinct version IDs, got '%s' twice", aws.ToString(versions[0].VersionId))
+	}
+}
+
+//go:embed testdata/fixtures
+var fixtures embed.FS
+
+func TestMinIOUploadDirectory(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	want := map[string]string{
+		"readme.txt":            "Hello, fixtures!\n",
+		"data/config.json":      "{\"name\":\"fixture\"}\n",
+		"images/icons/logo.png": "PNG",
+	}
+
+	assertObjects := func(t *testing.T, bucketName string) {
+		t.Helper()
+
+		keys, err := minio.ListObjects(t, client, bucketName, "")
+		if err != nil {
+			t.Fatalf("ListObjects failed: %v", err)
+		}
+		if len(keys) != len(want) {
+			t.Fatalf("Expected %d objects, got %v", len(want), keys)
+		}
+		for key, content := range want {
+			got, err := minio.DownloadObject(t, client, bucketName, key)
+			if err != nil {
+				t.Fatalf("DownloadObject failed: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("Object '%s' content mismatch. Expected '%s', got '%s'", key, content, got)
+			}
+		}
+	}
+
+	t.Run("Directory", func(t *testing.T) {
+		bucketName := "fixtures-dir"
+		if err := minio.PrepBucket(t, client, bucketName); err != nil {
+			t.Fatalf("PrepBucket failed: %v", err)
+		}
+		if err := minio.UploadDirectory(t, client, bucketName, "testdata/fixtures"); err != nil {
+			t.Fatalf("UploadDirectory failed: %v", err)
+		}
+		assertObjects(t, bucketName)
+	})
+
+	t.Run("EmbedFS", func(t *testing.T) {
+		bucketName := "fixtures-embed"
+		if err := minio.PrepBucket(t, client, bucketName); err != nil {
+			t.Fatalf("PrepBucket failed: %v", err)
+		}
+		if err := minio.UploadFS(t, client, bucketName, fixtures, "testdata/fixtures"); err != nil {
+			t.Fatalf("UploadFS failed: %v", err)
+		}
+		assertObjects(t, bucketName)
+	})
+}
+
+func TestMinIOEndpoint(t *testing.T) {
+	_, endpoint, cleanup := minio.RunWithEndpoint(t, nil)
+	defer cleanup()
+
+	// The console URL can be printed to inspect buckets interactively
+	t.Logf("MinIO console: %s", endpoint.ConsoleURL)
+
+	if endpoint.ConsoleURL == "" {
+		t.Error("Expected a non-empty console URL")
+	}
+	if endpoint.AccessKey != "minioadmin" || endpoint.SecretKey != "minioadmin" {
+		t.Errorf("Expected default credentials, got %s:%s", endpoint.AccessKey, endpoint.SecretKey)
+	}
+}
+
+func TestMinIOObjectTags(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	bucketName := "tagged-bucket"
+	key := "report.csv"
+	if err := minio.PrepS3Objects(t, client, bucketName, map[string][]byte{key: []byte("a,b,c")}); err != nil {
+		t.Fatalf("PrepS3Objects failed: %v", err)
+	}
+
+	tags := map[string]string{
+		"retention": "30d",
+		"owner":     "analytics",
+	}
+	if err := minio.SetObjectTags(t, client, bucketName, key, tags); err != nil {
+		t.Fatalf("SetObjectTags failed: %v", err)
+	}
+
+	got, err := minio.GetObjectTags(t, client, bucketName, key)
+	if err != nil {
+		t.Fatalf("GetObjectTags failed: %v", err)
+	}
+	if !maps.Equal(got, tags) {
+		t.Errorf("Expected tags %v, got %v", tags, got)
+	}
+}
+
+func TestMinIOEndpointURL(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "localhost", want: "http://localhost:55001"},
+		{host: "192.168.99.100", want: "http://192.168.99.100:55001"},
+		{host: "docker.internal", want: "http://docker.internal:55001"},
+		{host: "::1", want: "http://[::1]:55001"},
+	}
+	for _, tt := range tests {
+		if got := minio.EndpointURL(tt.host, "55001"); got != tt.want {
+			t.Errorf("EndpointURL(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+// TestMinIOResourceURL checks that the endpoint of a container follows DOCKER_HOST, so that a
+// remote Docker daemon is reached on its own host rather than on localhost.
+func TestMinIOResourceURL(t *testing.T) {
+	resource := &dockertest.Resource{
+		Container: &docker.Container{
+			NetworkSettings: &docker.NetworkSettings{
+				Ports: map[docker.Port][]docker.PortBinding{
+					"9000/tcp": {{HostIP: "0.0.0.0", HostPort: "55001"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		dockerHost string
+		want       string
+	}{
+		{name: "unix socket", dockerHost: "unix:///var/run/docker.sock", want: "http://localhost:55001"},
+		{name: "remote daemon", dockerHost: "tcp://192.168.99.100:2376", want: "http://192.168.99.100:55001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", tt.dockerHost)
+			t.Setenv("DOCKER_CERT_PATH", "")
+			t.Setenv("DOCKER_MACHINE_NAME", "")
+
+			pool, err := dockertest.NewPool("")
+			if err != nil {
+				t.Fatalf("failed to create pool: %v", err)
+			}
+
+			got, err := minio.ResourceURL(pool, resource, "9000/tcp")
+			if err != nil {
+				t.Fatalf("ResourceURL failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResourceURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	pool, err := dockertest.NewPool("unix:///var/run/docker.sock")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	if _, err := minio.ResourceURL(pool, resource, "9001/tcp"); err == nil {
+		t.Error("expected an error for a port that is not published")
+	}
+}
+
+func TestMinIOUploadMultipart(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	bucketName := "multipart-test"
+	if err := minio.PrepBucket(t, client, bucketName); err != nil {
+		t.Fatalf("PrepBucket failed: %v", err)
+	}
+
+	// 11 MiB split into 5 MiB parts gives three parts, the last one 1 MiB
+	const partSize = 5 << 20
+	body := make([]byte, 11<<20)
+	for i := range body {
+		body[i] = byte(i % 251)
+	}
+	if err := minio.UploadMultipart(t, client, bucketName, "large.bin", body, partSize); err != nil {
+		t.Fatalf("UploadMultipart failed: %v", err)
+	}
+
+	head, err := client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String("large.bin"),
+	})
+	if err != nil {
+		t.Fatalf("HeadObject failed: %v", err)
+	}
+	// The ETag of a multipart object ends with the number of parts
+	if etag := aws.ToString(head.ETag); !strings.HasSuffix(strings.Trim(etag, `"`), "-3") {
+		t.Errorf("Expected an ETag of a 3-part upload, got %s", etag)
+	}
+
+	got, err := minio.DownloadObject(t, client, bucketName, "large.bin")
+	if err != nil {
+		t.Fatalf("DownloadObject failed: %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("Assembled object does not match the uploaded body")
+	}
+
+	if err := minio.UploadMultipart(t, client, bucketName, "small-parts.bin", body, 1<<20); err == nil {
+		t.Errorf("Expected an error for a part size below 5 MiB")
+	}
+}
+
+func TestMinIOBucketNotifications(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	bucketName := "notification-test"
+	if err := minio.PrepBucket(t, client, bucketName); err != nil {
+		t.Fatalf("PrepBucket failed: %v", err)
+	}
+
+	events, stop := minio.EnableBucketNotifications(t, client, bucketName)
+	defer stop()
+
+	if err := minio.UploadObject(t, client, bucketName, "uploads/report 1.csv", []byte("a,b\n1,2\n")); err != nil {
+		t.Fatalf("UploadObject failed: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if !strings.HasPrefix(ev.Name, "s3:ObjectCreated:") {
+			t.Errorf("Expected an ObjectCreated event, got %s", ev.Name)
+		}
+		if ev.Bucket != bucketName || ev.Key != "uploads/report 1.csv" || ev.Size != 8 {
+			t.Errorf("Unexpected event: %+v", ev)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for an ObjectCreated event")
+	}
+}
+
+// TestMinIOBucketNotificationsStub runs EnableBucketNotifications against a stub of
+// MinIO's listen API.
+func TestMinIOBucketNotificationsStub(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" || r.URL.Query()["events"] == nil {
+			t.Errorf("Unexpected listen request: %s", r.URL)
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			t.Errorf("Expected a signed request, got Authorization %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// A keep-alive followed by one notification
+		io.WriteString(w, " \n")
+		io.WriteString(w, `{"Records":[{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"events"},"object":{"key":"a%2Fb.txt","size":3,"eTag":"abc"}}}]}`+"\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("minioadmin", "minioadmin", ""),
+		UsePathStyle: true,
+	})
+
+	events, stop := minio.EnableBucketNotifications(t, client, "events")
+	defer stop()
+
+	select {
+	case ev := <-events:
+		want := minio.Event{Name: "s3:ObjectCreated:Put", Bucket: "events", Key: "a/b.txt", Size: 3, ETag: "abc"}
+		if ev != want {
+			t.Errorf("Expected %+v, got %+v", want, ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+}
+
+func TestMinIOServerSideEncryption(t *testing.T) {
+	client, cleanup := minio.RunWithOptions(t, []func(*dockertest.RunOptions){
+		minio.WithKMS(),
+	})
+	defer cleanup()
+
+	bucketName := "sse-test"
+	if err := minio.PrepBucket(t, client, bucketName); err != nil {
+		t.Fatalf("PrepBucket failed: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		opts []minio.UploadOption
+		want types.ServerSideEncryption
+	}{
+		{key: "plain.txt", want: ""},
+		{key: "sse-s3.txt", opts: []minio.UploadOption{minio.WithServerSideEncryption(types.ServerSideEncryptionAes256)}, want: types.ServerSideEncryptionAes256},
+		{key: "sse-kms.txt", opts: []minio.UploadOption{minio.WithSSEKMS(minio.KMSKeyName)}, want: types.ServerSideEncryptionAwsKms},
+	}
+	for _, tt := range tests {
+		if err := minio.UploadObject(t, client, bucketName, tt.key, []byte("secret"), tt.opts...); err != nil {
+			t.Fatalf("UploadObject(%s) failed: %v", tt.key, err)
+		}
+		got, err := minio.GetObjectEncryption(t, client, bucketName, tt.key)
+		if err != nil {
+			t.Fatalf("GetObjectEncryption(%s) failed: %v", tt.key, err)
+		}
+		if got != tt.want {
+			t.Errorf("Expected %s to be stored with encryption %q, got %q", tt.key, tt.want, got)
+		}
+
+		// Encrypted objects are decrypted transparently on download
+		body, err := minio.DownloadObject(t, client, bucketName, tt.key)
+		if err != nil {
+			t.Fatalf("DownloadObject(%s) failed: %v", tt.key, err)
+		}
+		if string(body) != "secret" {
+			t.Errorf("Unexpected content of %s: %q", tt.key, body)
+		}
+	}
+}
+
+func TestMinIOCopyObject(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	content := []byte("copy me")
+	if err := minio.PrepS3Objects(t, client, "copy-src", map[string][]byte{"reports/2024 q1.txt": content}); err != nil {
+		t.Fatalf("PrepS3Objects failed: %v", err)
+	}
+	if err := minio.PrepBucket(t, client, "copy-dst"); err != nil {
+		t.Fatalf("PrepBucket failed: %v", err)
+	}
+
+	if err := minio.CopyObject(t, client, "copy-src", "reports/2024 q1.txt", "copy-dst", "archive/q1.txt"); err != nil {
+		t.Fatalf("CopyObject failed: %v", err)
+	}
+
+	got, err := minio.DownloadObject(t, client, "copy-dst", "archive/q1.txt")
+	if err != nil {
+		t.Fatalf("DownloadObject failed: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected copy content %q, got %q", content, got)
+	}
+
+	// The source object is left in place
+	if _, err := minio.DownloadObject(t, client, "copy-src", "reports/2024 q1.txt"); err != nil {
+		t.Errorf("source object is gone after the copy: %v", err)
+	}
+}
+
+// TestMinIONewPresignClient uploads an object with a presigned PUT URL and a plain http.Client.
+func TestMinIONewPresignClient(t *testing.T) {
+	client, cleanup := minio.Run(t)
+	defer cleanup()
+
+	bucketName := "presign-bucket"
+	if err := minio.PrepBucket(t, client, bucketName); err != nil {
+		t.Fatalf("PrepBucket failed: %v", err)
+	}
+
+	presigner := minio.NewPresignClient(client)
+	req, err := presigner.PresignPutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String("uploads/hello.txt"),
+	}, s3.WithPresignExpires(5*time.Minute))
+	if err != nil {
+		t.Fatalf("PresignPutObject failed: %v", err)
+	}
+
+	content := []byte("uploaded via presigned URL")
+	httpReq, err := http.NewRequest(req.Method, req.URL, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	for key, values := range req.SignedHeader {
+		if key != "Host" {
+			httpReq.Header[key] = values
+		}
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatalf("failed to upload via presigned URL: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected status 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	got, err := minio.DownloadObject(t, client, bucketName, "uploads/hello.txt")
+	if err != nil {
+		t.Fatalf("DownloadObject failed: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected content %q, got %q", content, got)
+	}
+}
+
+func TestMinIOCopySource(t *testing.T) {
+	tests := []struct {
+		bucket, key, want string
+	}{
+		{"bucket", "file.txt", "bucket/file.txt"},
+		{"bucket", "dir/sub/file.txt", "bucket/dir/sub/file.txt"},
+		{"bucket", "reports/2024 q1+final.txt", "bucket/reports/2024%20q1+final.txt"},
+	}
+	for _, tt := range tests {
+		if got := minio.CopySource(tt.bucket, tt.key); got != tt.want {
+			t.Errorf("CopySource(%q, %q) = %q, want %q", tt.bucket, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"testing"
+	"time"
+)
+
+const (
+	defaultMongoImage = "mongo"
+	defaultMongoTag   = "7"
+)
+
+// Run starts a MongoDB Docker container using the default settings and returns a connected
+// *mongo.Client along with a cleanup function. It uses the default MongoDB image ("mongo")
+// with tag "7". For more customization, use RunWithOptions.
+func Run(t testing.TB) (*mongo.Client, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts a MongoDB Docker container using Docker and returns a connected
+// *mongo.Client along with a cleanup function. It applies the default settings:
+//   - Repository: "mongo"
+//   - Tag: "7"
+//
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*mongo.Client, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	// Set default run options for MongoDB
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultMongoImage,
+		Tag:        defaultMongoTag,
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start mongo container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort("27017/tcp")
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the mongo container")
+	}
+	t.Logf("mongo container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure mongo container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	// Create MongoDB client
+	var client *mongo.Client
+
+	// Try to connect to MongoDB with retries
+	if err = settings.Retry(pool, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var err error
+		client, err = mongo.Connect(options.Client().ApplyURI(fmt.Sprintf("mongodb://%s/?directConnection=true", actualPort)))
+		if err != nil {
+			return err
+		}
+		// Ping the server to check if it's responsive
+		if err := client.Ping(ctx, nil); err != nil {
+			_ = client.Disconnect(ctx)
+			return err
+		}
+		return nil
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to mongo: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := client.Disconnect(context.Background()); err != nil {
+			t.Logf("failed to disconnect MongoDB client: %s", err)
+		}
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove mongo container: %s", err)
+		}
+	}
+
+	return client, cleanup
+}
+
+// PrepCollection sets up test data in a MongoDB collection.
+// It accepts the database and collection names and a list of documents to be inserted.
+// If any operation fails, it returns an error.
+func PrepCollection(t testing.TB, client *mongo.Client, db, coll string, docs []any) error {
+	t.Helper()
+
+	if len(docs) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	if _, err := client.Database(db).Collection(coll).InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to insert documents into collection '%s.%s': %w", db, coll, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package mongo_test
+
+import (
+	"context"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	mongotest "github.com/vvatanabe/dockertestx/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"testing"
+)
+
+// TestDefaultMongo demonstrates using Run with default options.
+func TestDefaultMongo(t *testing.T) {
+	// Start a MongoDB container with default options.
+	client, cleanup := mongotest.Run(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Seed the collection
+	type User struct {
+		Name string `bson:"name"`
+		Age  int    `bson:"age"`
+	}
+	docs := []any{
+		User{Name: "Alice", Age: 30},
+		User{Name: "Bob", Age: 25},
+	}
+	if err := mongotest.PrepCollection(t, client, "test", "users", docs); err != nil {
+		t.Fatalf("PrepCollection failed: %v", err)
+	}
+
+	// Read a document back
+	var got User
+	if err := client.Database("test").Collection("users").FindOne(ctx, bson.M{"name": "Alice"}).Decode(&got); err != nil {
+		t.Fatalf("failed to find document: %v", err)
+	}
+	if got.Age != 30 {
+		t.Errorf("expected age 30, got %d", got.Age)
+	}
+
+	// Verify the number of documents
+	count, err := client.Database("test").Collection("users").CountDocuments(ctx, bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count documents: %v", err)
+	}
+	if count != int64(len(docs)) {
+		t.Errorf("expected %d documents, got %d", len(docs), count)
+	}
+}
+
+// TestMongoWithCustomRunOptions demonstrates overriding default RunOptions.
+func TestMongoWithCustomRunOptions(t *testing.T) {
+	// Custom RunOption to override the default tag
+	customTag := func(opts *dockertest.RunOptions) {
+		opts.Tag = "6"
+	}
+
+	client, cleanup := mongotest.RunWithOptions(t, []func(*dockertest.RunOptions){customTag})
+	defer cleanup()
+
+	if err := client.Ping(context.Background(), nil); err != nil {
+		t.Fatalf("failed to ping: %v", err)
+	}
+}
+
+// TestMongoWithCustomHostOptions demonstrates providing host configuration options.
+func TestMongoWithCustomHostOptions(t *testing.T) {
+	// Host option to set AutoRemove to true
+	autoRemove := func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+	}
+
+	client, cleanup := mongotest.RunWithOptions(t, nil, autoRemove)
+	defer cleanup()
+
+	if err := client.Ping(context.Background(), nil); err != nil {
+		t.Fatalf("failed to ping: %v", err)
+	}
+}
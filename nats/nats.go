@@ -0,0 +1,108 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"testing"
+)
+
+const (
+	defaultNATSImage = "nats"
+	defaultNATSTag   = "2.10"
+	defaultNATSPort  = "4222/tcp"
+)
+
+// Run starts a NATS Docker container using the default settings and returns a connected
+// *nats.Conn along with a cleanup function. It uses the default NATS image ("nats") with tag "2.10"
+// and enables JetStream. For more customization, use RunWithOptions.
+func Run(t testing.TB) (*nats.Conn, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts a NATS Docker container using Docker and returns a connected
+// *nats.Conn along with a cleanup function. It applies the default settings:
+//   - Repository: "nats"
+//   - Tag: "2.10"
+//   - Cmd: ["-js"] (JetStream enabled)
+//
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*nats.Conn, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	// Set default run options for NATS
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultNATSImage,
+		Tag:        defaultNATSTag,
+		Cmd:        []string{"-js"},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start nats container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultNATSPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the nats container")
+	}
+	t.Logf("nats container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure nats container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	var conn *nats.Conn
+	if err = settings.Retry(pool, func() error {
+		var err error
+		conn, err = nats.Connect(fmt.Sprintf("nats://%s", actualPort))
+		return err
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to nats: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		conn.Close()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove nats container: %s", err)
+		}
+	}
+
+	return conn, cleanup
+}
+
+// PrepStream creates a JetStream stream with the given configuration.
+// If a stream with the same name already exists, its configuration is updated.
+// If the stream cannot be created, it returns an error.
+func PrepStream(t testing.TB, js jetstream.JetStream, cfg jetstream.StreamConfig) error {
+	t.Helper()
+
+	if _, err := js.CreateOrUpdateStream(context.Background(), cfg); err != nil {
+		return fmt.Errorf("failed to create stream '%s': %w", cfg.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package nats_test
+
+import (
+	"context"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/ory/dockertest/v3"
+	natstest "github.com/vvatanabe/dockertestx/nats"
+	"testing"
+	"time"
+)
+
+// TestDefaultNATS demonstrates publishing to a subject and receiving via a subscription.
+func TestDefaultNATS(t *testing.T) {
+	// Start a NATS container with default options.
+	conn, cleanup := natstest.Run(t)
+	defer cleanup()
+
+	sub, err := conn.SubscribeSync("greetings")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := conn.Publish("greetings", []byte("hello nats")); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	msg, err := sub.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	if string(msg.Data) != "hello nats" {
+		t.Errorf("expected 'hello nats', got '%s'", msg.Data)
+	}
+}
+
+// TestNATSJetStream demonstrates that messages published to a stream are persisted.
+func TestNATSJetStream(t *testing.T) {
+	conn, cleanup := natstest.Run(t)
+	defer cleanup()
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatalf("failed to create JetStream context: %v", err)
+	}
+
+	if err := natstest.PrepStream(t, js, jetstream.StreamConfig{
+		Name:     "ORDERS",
+		Subjects: []string{"orders.>"},
+	}); err != nil {
+		t.Fatalf("PrepStream failed: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// Publish before any consumer exists; the stream must keep the message
+	if _, err := js.Publish(ctx, "orders.created", []byte("order-1")); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	stream, err := js.Stream(ctx, "ORDERS")
+	if err != nil {
+		t.Fatalf("failed to get stream: %v", err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stream info: %v", err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("expected 1 stored message, got %d", info.State.Msgs)
+	}
+
+	// A consumer created afterwards still receives the message
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		AckPolicy: jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	batch, err := consumer.Fetch(1, jetstream.FetchMaxWait(5*time.Second))
+	if err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+	var got []string
+	for msg := range batch.Messages() {
+		got = append(got, string(msg.Data()))
+		_ = msg.Ack()
+	}
+	if len(got) != 1 || got[0] != "order-1" {
+		t.Errorf("expected [order-1], got %v", got)
+	}
+}
+
+// TestNATSWithCustomRunOptions demonstrates overriding default RunOptions.
+func TestNATSWithCustomRunOptions(t *testing.T) {
+	// Custom RunOption to override the default tag
+	customTag := func(opts *dockertest.RunOptions) {
+		opts.Tag = "2.9"
+	}
+
+	conn, cleanup := natstest.RunWithOptions(t, []func(*dockertest.RunOptions){customTag})
+	defer cleanup()
+
+	if !conn.IsConnected() {
+		t.Error("expected the connection to be established")
+	}
+}
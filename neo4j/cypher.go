@@ -0,0 +1,94 @@
+package neo4j
+
+import (
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"os"
+	"strings"
+	"testing"
+)
+
+// PrepCypherFile reads the Cypher script at path, e.g. a ".cypher" fixtures file, splits it into
+// statements on the semicolons that end them and runs them in order with PrepCypher, each in its own
+// write transaction. Semicolons inside string literals, backtick-quoted names and comments do not end
+// a statement, and comments are removed. If the file cannot be read or a statement fails, it returns
+// an error; the preceding statements remain applied.
+func PrepCypherFile(t testing.TB, driver neo4j.DriverWithContext, path string) error {
+	t.Helper()
+
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read cypher file: %w", err)
+	}
+	if err := PrepCypher(t, driver, splitCypher(string(script))); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// splitCypher splits a Cypher script into statements on ";". It understands single- and
+// double-quoted strings with backslash escapes, backtick-quoted names with doubled backticks,
+// "//" line comments and "/* */" block comments, which are removed.
+// Empty statements are dropped and the remaining ones are returned without their semicolon.
+func splitCypher(script string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		cur.Reset()
+	}
+
+	n := len(script)
+	for i := 0; i < n; {
+		rest := script[i:]
+		switch c := script[i]; {
+		case c == ';':
+			flush()
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for j < n {
+				if script[j] == '\\' && c != '`' {
+					j += 2
+					continue
+				}
+				if script[j] == c {
+					// A doubled backtick is an escaped backtick inside a name.
+					if c == '`' && j+1 < n && script[j+1] == '`' {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			j = min(j, n)
+			cur.WriteString(script[i:j])
+			i = j
+		case strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				i = n
+			} else {
+				i += end + 4
+			}
+			cur.WriteByte(' ')
+		default:
+			cur.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return stmts
+}
@@ -0,0 +1,4 @@
+package neo4j
+
+// SplitCypher exposes splitCypher to the external test package.
+var SplitCypher = splitCypher
@@ -0,0 +1,141 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	defaultNeo4jImage = "neo4j"
+	defaultNeo4jTag   = "5"
+	defaultBoltPort   = "7687/tcp"
+	defaultNeo4jAuth  = "neo4j/testpassword"
+	// The system database is created and the initial password set before Bolt accepts logins.
+	defaultNeo4jMaxWait = 2 * time.Minute
+)
+
+// Run starts a Neo4j Docker container using the default settings and returns a connected
+// neo4j.DriverWithContext along with a cleanup function. It uses the default Neo4j image ("neo4j")
+// with tag "5". For more customization, use RunWithOptions.
+func Run(t testing.TB) (neo4j.DriverWithContext, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts a Neo4j Docker container using Docker and returns a connected
+// neo4j.DriverWithContext along with a cleanup function. It applies the default settings:
+//   - Repository: "neo4j"
+//   - Tag: "5"
+//   - Environment: NEO4J_AUTH=neo4j/testpassword
+//
+// The driver connects over Bolt with the credentials from NEO4J_AUTH ("none" disables authentication).
+// The container is considered ready once VerifyConnectivity succeeds.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (neo4j.DriverWithContext, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+	pool.MaxWait = defaultNeo4jMaxWait
+
+	// Set default run options for Neo4j
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultNeo4jImage,
+		Tag:        defaultNeo4jTag,
+		Env: []string{
+			"NEO4J_AUTH=" + defaultNeo4jAuth,
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	auth := neo4j.NoAuth()
+	if user, pass, ok := strings.Cut(internal.GetEnvValue(defaultRunOpts.Env, "NEO4J_AUTH"), "/"); ok {
+		auth = neo4j.BasicAuth(user, pass, "")
+	}
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start neo4j container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultBoltPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the neo4j container")
+	}
+	t.Logf("neo4j container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure neo4j container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	driver, err := neo4j.NewDriverWithContext(fmt.Sprintf("bolt://%s", actualPort), auth)
+	if err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to create neo4j driver: %s", err)
+	}
+
+	if err = settings.Retry(pool, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return driver.VerifyConnectivity(ctx)
+	}); err != nil {
+		logs.Dump()
+		_ = driver.Close(context.Background())
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to neo4j: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := driver.Close(context.Background()); err != nil {
+			t.Logf("failed to close neo4j driver: %s", err)
+		}
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove neo4j container: %s", err)
+		}
+	}
+
+	return driver, cleanup
+}
+
+// PrepCypher sets up test data by running the given Cypher statements in order, each in its own
+// auto-commit transaction, so that schema statements such as CREATE INDEX can be mixed with writes.
+// If a statement fails, it returns an error naming its index; the preceding statements remain applied.
+func PrepCypher(t testing.TB, driver neo4j.DriverWithContext, statements []string) error {
+	t.Helper()
+
+	ctx := context.Background()
+	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	for i, stmt := range statements {
+		result, err := session.Run(ctx, stmt, nil)
+		if err == nil {
+			_, err = result.Consume(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to run cypher statement %d: %w", i, err)
+		}
+	}
+	return nil
+}
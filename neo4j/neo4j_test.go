@@ -0,0 +1,110 @@
+package neo4j_test
+
+import (
+	"context"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	neo4jtest "github.com/vvatanabe/dockertestx/neo4j"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestDefaultNeo4j demonstrates seeding a small graph and matching a path.
+func TestDefaultNeo4j(t *testing.T) {
+	// Start a Neo4j container with default options.
+	driver, cleanup := neo4jtest.Run(t)
+	defer cleanup()
+
+	if err := neo4jtest.PrepCypher(t, driver, []string{
+		`CREATE CONSTRAINT person_name IF NOT EXISTS FOR (p:Person) REQUIRE p.name IS UNIQUE`,
+		`CREATE (:Person {name: 'Alice'})-[:KNOWS]->(:Person {name: 'Bob'})`,
+		`MATCH (b:Person {name: 'Bob'}) CREATE (b)-[:KNOWS]->(:Person {name: 'Carol'})`,
+	}); err != nil {
+		t.Fatalf("PrepCypher failed: %v", err)
+	}
+
+	ctx := context.Background()
+	result, err := neo4j.ExecuteQuery(ctx, driver,
+		`MATCH p = (:Person {name: $from})-[:KNOWS*]->(:Person {name: $to})
+		 RETURN [n IN nodes(p) | n.name] AS names`,
+		map[string]any{"from": "Alice", "to": "Carol"},
+		neo4j.EagerResultTransformer,
+	)
+	if err != nil {
+		t.Fatalf("failed to run MATCH query: %v", err)
+	}
+	if len(result.Records) != 1 {
+		t.Fatalf("expected 1 path, got %d", len(result.Records))
+	}
+
+	names, _ := result.Records[0].Get("names")
+	want := []any{"Alice", "Bob", "Carol"}
+	got, ok := names.([]any)
+	if !ok || len(got) != len(want) {
+		t.Fatalf("expected path %v, got %v", want, names)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected path %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+// TestPrepCypherFile seeds a social graph from a fixtures file and queries the friends of friends.
+func TestPrepCypherFile(t *testing.T) {
+	driver, cleanup := neo4jtest.Run(t)
+	defer cleanup()
+
+	if err := neo4jtest.PrepCypherFile(t, driver, filepath.Join("testdata", "social.cypher")); err != nil {
+		t.Fatalf("PrepCypherFile failed: %v", err)
+	}
+
+	ctx := context.Background()
+	result, err := neo4j.ExecuteQuery(ctx, driver,
+		`MATCH (me:Person {name: $name})-[:FRIEND]-(friend)-[:FRIEND]-(fof)
+		 WHERE fof <> me AND NOT (me)-[:FRIEND]-(fof)
+		 RETURN DISTINCT fof.name AS name ORDER BY name`,
+		map[string]any{"name": "Alice"},
+		neo4j.EagerResultTransformer,
+	)
+	if err != nil {
+		t.Fatalf("failed to run friends-of-friends query: %v", err)
+	}
+
+	var got []string
+	for _, record := range result.Records {
+		name, _ := record.Get("name")
+		got = append(got, name.(string))
+	}
+	if want := []string{"Carol", "Dave"}; !slices.Equal(got, want) {
+		t.Errorf("expected friends of friends %v, got %v", want, got)
+	}
+
+	// The semicolon inside the string literal did not split the statement
+	result, err = neo4j.ExecuteQuery(ctx, driver, `MATCH (p:Person {name: 'Alice'}) RETURN p.bio AS bio`, nil, neo4j.EagerResultTransformer)
+	if err != nil {
+		t.Fatalf("failed to query bio: %v", err)
+	}
+	if len(result.Records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(result.Records))
+	}
+	if bio, _ := result.Records[0].Get("bio"); bio != "Likes semicolons; really" {
+		t.Errorf("unexpected bio %v", bio)
+	}
+}
+
+func TestSplitCypher(t *testing.T) {
+	script := "// comment; ignored\n" +
+		"CREATE (:A {s: 'a;b', d: \"c\\\";d\"});\n" +
+		"/* block; */ MATCH (n:`we;ird``name`) RETURN n ;;\n" +
+		"RETURN 1"
+	want := []string{
+		`CREATE (:A {s: 'a;b', d: "c\";d"})`,
+		"MATCH (n:`we;ird``name`) RETURN n",
+		"RETURN 1",
+	}
+	if got := neo4jtest.SplitCypher(script); !slices.Equal(got, want) {
+		t.Errorf("SplitCypher() = %q, want %q", got, want)
+	}
+}
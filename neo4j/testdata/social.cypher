@@ -0,0 +1,12 @@
+// A small social graph: Alice and Bob are friends, and so are Bob and Carol, Bob and Dave.
+CREATE CONSTRAINT person_name IF NOT EXISTS FOR (p:Person) REQUIRE p.name IS UNIQUE;
+
+CREATE (:Person {name: 'Alice', bio: 'Likes semicolons; really'});
+CREATE (:Person {name: 'Bob'});
+CREATE (:Person {name: 'Carol'});
+CREATE (:Person {name: 'Dave'});
+
+/* Friendships are stored in one direction; queries ignore it. */
+MATCH (a:Person {name: 'Alice'}), (b:Person {name: 'Bob'}) CREATE (a)-[:FRIEND]->(b);
+MATCH (b:Person {name: 'Bob'}), (c:Person {name: 'Carol'}) CREATE (b)-[:FRIEND]->(c);
+MATCH (b:Person {name: 'Bob'}), (d:Person {name: 'Dave'}) CREATE (b)-[:FRIEND]->(d);
@@ -0,0 +1,174 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"testing"
+	"time"
+)
+
+const (
+	defaultOpenSearchImage = "opensearchproject/opensearch"
+	defaultOpenSearchTag   = "2.17.1"
+	defaultOpenSearchPort  = "9200/tcp"
+	// The JVM starts and the cluster state is recovered before the cluster reports a healthy status.
+	defaultOpenSearchMaxWait = 3 * time.Minute
+)
+
+// Run starts an OpenSearch Docker container using the default settings and returns a connected
+// *opensearchapi.Client along with a cleanup function. It uses the default OpenSearch image
+// ("opensearchproject/opensearch") with tag "2.17.1". For more customization, use RunWithOptions.
+func Run(t testing.TB) (*opensearchapi.Client, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts an OpenSearch Docker container using Docker and returns a connected
+// *opensearchapi.Client along with a cleanup function. It applies the default settings:
+//   - Repository: "opensearchproject/opensearch"
+//   - Tag: "2.17.1"
+//   - Environment: single-node discovery with the security plugin disabled and a 512MB heap
+//
+// The container is considered ready once the cluster health endpoint reports yellow or green.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*opensearchapi.Client, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+	pool.MaxWait = defaultOpenSearchMaxWait
+
+	// Set default run options for OpenSearch
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultOpenSearchImage,
+		Tag:        defaultOpenSearchTag,
+		Env: []string{
+			"discovery.type=single-node",
+			"DISABLE_SECURITY_PLUGIN=true",
+			"DISABLE_INSTALL_DEMO_CONFIG=true",
+			"OPENSEARCH_JAVA_OPTS=-Xms512m -Xmx512m",
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start opensearch container: %s", err)
+	}
+
+	actualPort := resource.GetHostPort(defaultOpenSearchPort)
+	if actualPort == "" {
+		_ = pool.Purge(resource)
+		t.Fatal("no host port was assigned for the opensearch container")
+	}
+	t.Logf("opensearch container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure opensearch container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	client, err := opensearchapi.NewClient(opensearchapi.Config{
+		Client: opensearch.Config{
+			Addresses: []string{fmt.Sprintf("http://%s", actualPort)},
+		},
+	})
+	if err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to create opensearch client: %s", err)
+	}
+
+	// Wait until the cluster reports yellow or green
+	if err = settings.Retry(pool, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		health, err := client.Cluster.Health(ctx, &opensearchapi.ClusterHealthReq{
+			Params: opensearchapi.ClusterHealthParams{
+				WaitForStatus: "yellow",
+				Timeout:       5 * time.Second,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if health.Status != "yellow" && health.Status != "green" {
+			return fmt.Errorf("cluster status is %q", health.Status)
+		}
+		return nil
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to opensearch: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove opensearch container: %s", err)
+		}
+	}
+
+	return client, cleanup
+}
+
+// PrepIndex sets up test data in an OpenSearch index.
+// It bulk-indexes the given documents and refreshes the index so that they are immediately searchable.
+// If the request or any individual document fails, it returns an error.
+func PrepIndex(t testing.TB, client *opensearchapi.Client, index string, docs []any) error {
+	t.Helper()
+
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	for i, doc := range docs {
+		body.WriteString(`{"index":{}}` + "\n")
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to marshal document %d: %w", i, err)
+		}
+		body.Write(b)
+		body.WriteByte('\n')
+	}
+
+	res, err := client.Bulk(context.Background(), opensearchapi.BulkReq{
+		Index: index,
+		Body:  &body,
+		Params: opensearchapi.BulkParams{
+			Refresh: "true",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to bulk index documents into '%s': %w", index, err)
+	}
+	if res.Errors {
+		for i, item := range res.Items {
+			for _, op := range item {
+				if op.Error != nil {
+					return fmt.Errorf("failed to index document %d into '%s': %s: %s", i, index, op.Error.Type, op.Error.Reason)
+				}
+			}
+		}
+		return fmt.Errorf("failed to bulk index documents into '%s'", index)
+	}
+	return nil
+}
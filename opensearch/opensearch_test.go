@@ -0,0 +1,52 @@
+package opensearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	ostest "github.com/vvatanabe/dockertestx/opensearch"
+	"strings"
+	"testing"
+)
+
+type product struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// TestDefaultOpenSearch demonstrates indexing documents and running a term query.
+func TestDefaultOpenSearch(t *testing.T) {
+	// Start an OpenSearch container with default options.
+	client, cleanup := ostest.Run(t)
+	defer cleanup()
+
+	docs := []any{
+		product{Name: "Laptop", Category: "electronics"},
+		product{Name: "Desk", Category: "furniture"},
+		product{Name: "Phone", Category: "electronics"},
+	}
+	if err := ostest.PrepIndex(t, client, "products", docs); err != nil {
+		t.Fatalf("PrepIndex failed: %v", err)
+	}
+
+	// Run a term query against the keyword sub-field created by dynamic mapping
+	query := `{"query":{"term":{"category.keyword":"furniture"}}}`
+	res, err := client.Search(context.Background(), &opensearchapi.SearchReq{
+		Indices: []string{"products"},
+		Body:    strings.NewReader(query),
+	})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+
+	if len(res.Hits.Hits) != 1 {
+		t.Fatalf("expected 1 hit, got %d", len(res.Hits.Hits))
+	}
+	var got product
+	if err := json.Unmarshal(res.Hits.Hits[0].Source, &got); err != nil {
+		t.Fatalf("failed to decode hit: %v", err)
+	}
+	if got.Name != "Desk" {
+		t.Errorf("expected name 'Desk', got '%s'", got.Name)
+	}
+}
@@ -0,0 +1,255 @@
+package dockertestx
+
+import (
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WithLogger returns a RunOption that writes the container's stdout and stderr into the test log
+// when the container fails to become ready. It is accepted by the RunWithOptions functions of
+// every package as well as RunContainer.
+func WithLogger() func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		internal.SettingsOf(opts).DumpLogs = true
+	}
+}
+
+// WithLogFollow returns a RunOption that streams the container's stdout and stderr into the test
+// log from the moment the container starts until its cleanup function is called. It is useful for
+// debugging flaky startups.
+func WithLogFollow() func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		internal.SettingsOf(opts).FollowLogs = true
+	}
+}
+
+// WithResource returns a RunOption that stores the started container's *dockertest.Resource in dst.
+// It gives access to the container itself, e.g. for resource.Exec, from Run functions that only
+// return a client. dst is set as soon as the container has been created and remains valid until
+// the cleanup function is called. With functions that start several containers, dst refers to the
+// last one started.
+func WithResource(dst **dockertest.Resource) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		internal.SettingsOf(opts).ResourceDst = dst
+	}
+}
+
+// WithVolume returns a RunOption that mounts the named Docker volume at mountPath inside the
+// container. Docker creates the volume if it does not exist. Unlike the container, the volume is
+// not removed by the cleanup function, so its data can outlive the container and be attached to
+// another one; remove it with (*docker.Client).RemoveVolumeWithOptions when it is no longer needed.
+func WithVolume(name, mountPath string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Mounts = append(opts.Mounts, name+":"+mountPath)
+	}
+}
+
+// WithBindMount returns a RunOption that bind-mounts the file or directory at hostPath into the
+// container at containerPath, e.g. a fixtures directory or a configuration file. A relative hostPath
+// is resolved against the current working directory, which is the package directory under go test.
+// With readOnly, the container cannot modify the mounted files. The files must be readable by the
+// user the container runs as, which is often not root.
+func WithBindMount(hostPath, containerPath string, readOnly bool) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		if abs, err := filepath.Abs(hostPath); err == nil {
+			hostPath = abs
+		}
+		mount := hostPath + ":" + containerPath
+		if readOnly {
+			mount += ":ro"
+		}
+		opts.Mounts = append(opts.Mounts, mount)
+	}
+}
+
+// WithMemoryLimit returns a host option that caps the container's memory at the given number of bytes.
+// Swap is capped at the same value, so the container cannot use swap to exceed the limit.
+// It can be passed as a hostOpts argument to every RunWithOptions function.
+func WithMemoryLimit(bytes int64) func(*docker.HostConfig) {
+	return func(hc *docker.HostConfig) {
+		hc.Memory = bytes
+		hc.MemorySwap = bytes
+	}
+}
+
+// WithCPUShares returns a host option that sets the container's relative CPU weight (1024 is the
+// Docker default). It only takes effect when containers compete for CPU time.
+// It can be passed as a hostOpts argument to every RunWithOptions function.
+func WithCPUShares(shares int64) func(*docker.HostConfig) {
+	return func(hc *docker.HostConfig) {
+		hc.CPUShares = shares
+	}
+}
+
+// WithLabels returns a RunOption that adds the given labels to the container, e.g. a project name
+// or a CI run ID, so that cleanup tooling can find containers left behind by crashed test runs.
+// Labels that are already set with the same key are overwritten.
+func WithLabels(labels map[string]string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		if opts.Labels == nil {
+			opts.Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			opts.Labels[k] = v
+		}
+	}
+}
+
+// WithPublishedPort returns a RunOption that publishes containerPort (e.g. "6379/tcp") on the given
+// fixed hostPort instead of an ephemeral one, e.g. to match a hard-coded configuration or to keep
+// the address stable across Restart. Starting the container fails if hostPort is already in use,
+// so fixed ports can conflict between parallel tests or with services on the host; prefer the
+// ephemeral mapping unless a fixed port is really needed.
+func WithPublishedPort(containerPort, hostPort string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		if opts.PortBindings == nil {
+			opts.PortBindings = make(map[docker.Port][]docker.PortBinding)
+		}
+		opts.PortBindings[docker.Port(containerPort)] = []docker.PortBinding{{HostIP: "0.0.0.0", HostPort: hostPort}}
+		for _, p := range opts.ExposedPorts {
+			if p == containerPort {
+				return
+			}
+		}
+		opts.ExposedPorts = append(opts.ExposedPorts, containerPort)
+	}
+}
+
+// WithHostname returns a RunOption that sets the container's hostname, as seen from inside the
+// container. Other containers resolve a container by its network aliases, not its hostname, so it
+// is typically combined with WithNetworkAlias using the same name.
+func WithHostname(name string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Hostname = name
+	}
+}
+
+// WithNetworkAlias returns a RunOption that adds alias to the names under which other containers on
+// the same user-defined network (see dockertest.RunOptions.Networks) reach the container, e.g. so
+// that an application container can connect to "postgres:5432" instead of a mapped host port.
+// It can be passed several times. Aliases have no effect on Docker's default bridge network.
+func WithNetworkAlias(alias string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		s := internal.SettingsOf(opts)
+		s.NetworkAliases = append(s.NetworkAliases, alias)
+	}
+}
+
+// WithExtraHosts returns a RunOption that adds entries to the container's /etc/hosts, each in the
+// form "name:ip", e.g. "host.docker.internal:host-gateway" to reach the Docker host from inside
+// the container on Linux, where Docker does not define that name by default. It can be passed
+// several times; the entries are appended.
+func WithExtraHosts(hosts ...string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.ExtraHosts = append(opts.ExtraHosts, hosts...)
+	}
+}
+
+// WithDNS returns a RunOption that sets the DNS servers used by the container, e.g. a resolver
+// started by the test that serves custom records, instead of those inherited from the Docker host.
+// It can be passed several times; the servers are appended.
+func WithDNS(servers ...string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.DNS = append(opts.DNS, servers...)
+	}
+}
+
+// WithBackoff returns a RunOption that waits between readiness attempts with an exponential backoff
+// instead of the default retry policy: the first wait is initial, and each following wait is factor
+// times the previous one, capped at max. The total wait is still bounded by the package's timeout
+// (pool.MaxWait). Longer waits reduce log noise and load on the Docker API for services that take
+// long to start. A factor below 1 is treated as 1, i.e. a fixed interval.
+func WithBackoff(initial, max time.Duration, factor float64) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		internal.SettingsOf(opts).Backoff = &internal.Backoff{Initial: initial, Max: max, Factor: factor}
+	}
+}
+
+// WithEntrypoint returns a RunOption that replaces the image's entrypoint, e.g. to bypass a wrapper
+// script that prepares a production setup, or to run the image's binary in a test-friendly mode.
+// The container's command (Cmd) is still passed to the new entrypoint as arguments, so set it as
+// well when the image's default command does not fit the new entrypoint.
+func WithEntrypoint(entrypoint ...string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Entrypoint = entrypoint
+	}
+}
+
+// WithUser returns a RunOption that runs the container's processes as user, in any form docker run
+// --user accepts: a name, a UID, or "uid:gid". It is needed for hardened images that refuse to run
+// as root, and to reproduce the permissions of a production deployment.
+func WithUser(user string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.User = user
+	}
+}
+
+// WithWorkingDir returns a RunOption that sets the directory the container's processes start in,
+// e.g. for images that look up their configuration relative to it. It replaces the image's WORKDIR.
+func WithWorkingDir(dir string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.WorkingDir = dir
+	}
+}
+
+// WithEnvMap returns a RunOption that sets the container's environment variables from env, e.g.
+// when they come from configuration. A variable that is already set, such as a package default,
+// is replaced in place; the others are appended in key order.
+func WithEnvMap(env map[string]string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		set := make(map[string]bool, len(env))
+		merged := make([]string, 0, len(opts.Env)+len(env))
+		for _, kv := range opts.Env {
+			key, _, _ := strings.Cut(kv, "=")
+			value, ok := env[key]
+			if !ok {
+				merged = append(merged, kv)
+				continue
+			}
+			// Later duplicates of a replaced key are dropped.
+			if !set[key] {
+				merged = append(merged, key+"="+value)
+				set[key] = true
+			}
+		}
+
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			if !set[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			merged = append(merged, key+"="+env[key])
+		}
+		opts.Env = merged
+	}
+}
+
+// WithUnquotedEnv returns a RunOption that removes one pair of matching double or single quotes
+// surrounding the values of the given environment variables, e.g. a MYSQL_ROOT_PASSWORD="p@ss=word"
+// taken from a .env file. Docker would pass the quotes to the container as part of the value, and the
+// client would connect with them too. Within double quotes, Go escape sequences such as \" are
+// interpreted; single-quoted values are taken literally. This only loosely resembles shell-style
+// assignment: there is no variable expansion. The values are rewritten when the option is applied, so
+// pass it after the options that set the variables.
+func WithUnquotedEnv(keys ...string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Env = internal.UnquoteEnvValues(opts.Env, keys...)
+	}
+}
+
+// WithRetryHook returns a RunOption that calls hook after each failed readiness attempt with the
+// attempt number, starting at 1, and the attempt's error, e.g. to report slow startups to a custom
+// logger or metrics. The hook is called from the goroutine running the Run function.
+func WithRetryHook(hook func(attempt int, err error)) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		internal.SettingsOf(opts).RetryHook = hook
+	}
+}
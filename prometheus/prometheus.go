@@ -0,0 +1,162 @@
+// Package prometheus provides helpers to run a Prometheus server that scrapes targets described
+// by a test and to query the collected metrics with PromQL.
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/vvatanabe/dockertestx/internal"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	defaultPrometheusImage = "prom/prometheus"
+	defaultPrometheusTag   = "v2.55.1"
+	defaultPrometheusPort  = "9090/tcp"
+	configPath             = "/etc/prometheus/prometheus.yml"
+
+	// HostGateway is the host name under which the Prometheus container reaches the host running
+	// the test, e.g. to scrape an exporter served by the test process on "host.docker.internal:8080".
+	HostGateway = "host.docker.internal"
+
+	// queryTimeout bounds a single PromQL query.
+	queryTimeout = 10 * time.Second
+)
+
+// Run starts a Prometheus Docker container configured with scrapeConfigYAML and returns the base
+// URL of its HTTP API (e.g. "http://localhost:55001") along with a cleanup function. It uses the
+// default Prometheus image ("prom/prometheus") with tag "v2.55.1". For more customization, use
+// RunWithOptions.
+func Run(t testing.TB, scrapeConfigYAML string) (string, func()) {
+	return RunWithOptions(t, scrapeConfigYAML, nil)
+}
+
+// RunWithOptions starts a Prometheus Docker container using Docker and returns the base URL of its
+// HTTP API along with a cleanup function. It applies the default settings:
+//   - Repository: "prom/prometheus"
+//   - Tag: "v2.55.1"
+//   - Configuration: scrapeConfigYAML, mounted at /etc/prometheus/prometheus.yml
+//   - Extra hosts: HostGateway resolves to the Docker host
+//
+// scrapeConfigYAML is a complete Prometheus configuration file. Keep scrape_interval short
+// (e.g. "1s"), since tests usually wait for the first scrape. The container is considered ready
+// once /-/ready responds with 200 OK.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, scrapeConfigYAML string, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (string, func()) {
+	t.Helper()
+
+	config := filepath.Join(t.TempDir(), "prometheus.yml")
+	if err := os.WriteFile(config, []byte(scrapeConfigYAML), 0o644); err != nil {
+		t.Fatalf("failed to write prometheus config: %s", err)
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	// Set default run options for Prometheus
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository:   defaultPrometheusImage,
+		Tag:          defaultPrometheusTag,
+		Mounts:       []string{config + ":" + configPath + ":ro"},
+		ExposedPorts: []string{defaultPrometheusPort},
+		ExtraHosts:   []string{HostGateway + ":host-gateway"},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start prometheus container: %s", err)
+	}
+
+	host, port, err := internal.HostAddress(pool, resource, defaultPrometheusPort)
+	if err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to get the address of the prometheus container: %s", err)
+	}
+	actualPort := net.JoinHostPort(host, port)
+	t.Logf("prometheus container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure prometheus container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	baseURL := fmt.Sprintf("http://%s", actualPort)
+
+	// Wait until Prometheus is ready to serve queries
+	if err = settings.Retry(pool, func() error {
+		return checkReady(baseURL)
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to prometheus: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove prometheus container: %s", err)
+		}
+	}
+
+	return baseURL, cleanup
+}
+
+// checkReady queries Prometheus' /-/ready endpoint and returns an error unless it responds with 200 OK.
+func checkReady(baseURL string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(baseURL + "/-/ready")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("prometheus is not ready yet (status %d)", resp.StatusCode)
+	}
+	return nil
+}
+
+// Query evaluates the PromQL expression promQL at the current time against the Prometheus server
+// at baseURL and returns the result, typically a model.Vector. Warnings reported by Prometheus are
+// written to the test log. If the query fails, it returns an error.
+func Query(t testing.TB, baseURL, promQL string) (model.Value, error) {
+	t.Helper()
+
+	client, err := api.NewClient(api.Config{Address: baseURL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prometheus client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	value, warnings, err := v1.NewAPI(client).Query(ctx, promQL, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query '%s': %w", promQL, err)
+	}
+	for _, w := range warnings {
+		t.Logf("prometheus warning for query '%s': %s", promQL, w)
+	}
+	return value, nil
+}
@@ -0,0 +1,63 @@
+package prometheus_test
+
+import (
+	"fmt"
+	"github.com/prometheus/common/model"
+	"github.com/vvatanabe/dockertestx/prometheus"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPrometheus scrapes a stub exporter served by the test process and queries its metric.
+func TestPrometheus(t *testing.T) {
+	// Listen on all interfaces so that the container can reach the exporter through the host gateway
+	listener, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	exporter := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, "# TYPE orders_processed_total counter")
+		fmt.Fprintln(w, `orders_processed_total{status="ok"} 42`)
+	}))
+	exporter.Listener = listener
+	exporter.Start()
+	defer exporter.Close()
+
+	config := fmt.Sprintf(`global:
+  scrape_interval: 1s
+scrape_configs:
+  - job_name: stub
+    static_configs:
+      - targets: ["%s:%d"]
+`, prometheus.HostGateway, listener.Addr().(*net.TCPAddr).Port)
+
+	baseURL, cleanup := prometheus.Run(t, config)
+	defer cleanup()
+
+	// Wait for the first scrape
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		value, err := prometheus.Query(t, baseURL, `orders_processed_total{job="stub",status="ok"}`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		vector, ok := value.(model.Vector)
+		if !ok {
+			t.Fatalf("expected a vector, got %s", value.Type())
+		}
+		if len(vector) == 1 {
+			if vector[0].Value != 42 {
+				t.Errorf("expected value 42, got %v", vector[0].Value)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("metric was not scraped within 30 seconds")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -1,24 +1,55 @@
+// Package rabbitmq provides helpers to run a RabbitMQ container and prepare queues,
+// exchanges, and messages for tests.
+//
+// The package is built on github.com/rabbitmq/amqp091-go, the maintained successor of
+// github.com/streadway/amqp. Its API is nearly identical, so callers migrating from an
+// earlier version of this package only need to change the amqp import path.
 package rabbitmq
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
-	"github.com/streadway/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/vvatanabe/dockertestx/internal"
 )
 
 const (
 	defaultRabbitMQImage = "rabbitmq"
 	defaultRabbitMQTag   = "3-management"
 	defaultAMQPPort      = "5672/tcp"
+	defaultAMQPSPort     = "5671/tcp"
+	defaultMgmtPort      = "15672/tcp"
+	defaultUser          = "guest"
+	defaultPassword      = "guest"
 )
 
+// Endpoint describes where a RabbitMQ container started by RunWithEndpoint can be reached
+// from the host.
+type Endpoint struct {
+	// AMQPHostPort is the "host:port" mapped to the AMQP listener (5672/tcp).
+	AMQPHostPort string
+	// ManagementURL is the base URL of the management HTTP API (15672/tcp), e.g. "http://localhost:55001".
+	// It is empty when the image does not ship the management plugin.
+	ManagementURL string
+}
+
 // Run starts a RabbitMQ Docker container using the default settings and returns a connected
 // *amqp.Connection along with a cleanup function. It uses the default RabbitMQ image ("rabbitmq")
 // with tag "3-management". For more customization, use RunWithOptions.
+//
+// The returned connection is an amqp091-go *amqp.Connection (github.com/rabbitmq/amqp091-go);
+// it replaces the streadway/amqp type returned by earlier versions.
 func Run(t testing.TB) (*amqp.Connection, func()) {
 	return RunWithOptions(t, nil)
 }
@@ -33,6 +64,83 @@ func Run(t testing.TB) (*amqp.Connection, func()) {
 func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*amqp.Connection, func()) {
 	t.Helper()
 
+	conn, _, cleanup := RunWithEndpoint(t, runOpts, hostOpts...)
+	return conn, cleanup
+}
+
+// RunWithEndpoint behaves like RunWithOptions but additionally returns the Endpoint of the
+// started container, so that callers can dial further connections (e.g. with ConnectVHost)
+// or talk to the management API (e.g. with PrepVHost).
+func RunWithEndpoint(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*amqp.Connection, Endpoint, func()) {
+	t.Helper()
+
+	return run(t, runOpts, hostOpts, defaultAMQPPort, func(hostPort string) (*amqp.Connection, error) {
+		return amqp.Dial(amqpURL(hostPort, ""))
+	})
+}
+
+// RunWithTLS starts a RabbitMQ Docker container with the AMQPS listener (5671/tcp) enabled and
+// returns a *amqp.Connection dialed over TLS with amqp.DialTLS, along with a cleanup function.
+//
+// The first certificate of tlsConfig is mounted into the container as the server certificate,
+// and tlsConfig itself is used as the client configuration, so it should also trust that
+// certificate (e.g. via RootCAs). If tlsConfig is nil, a self-signed configuration is generated
+// with NewSelfSignedTLSConfig.
+func RunWithTLS(t testing.TB, tlsConfig *tls.Config) (*amqp.Connection, func()) {
+	t.Helper()
+
+	if tlsConfig == nil {
+		tlsConfig = NewSelfSignedTLSConfig(t)
+	}
+
+	dir := t.TempDir()
+	if _, _, _, err := internal.WriteTLSFiles(dir, tlsConfig); err != nil {
+		t.Fatalf("failed to write TLS files: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tls.conf"), []byte(tlsListenerConf), 0o644); err != nil {
+		t.Fatalf("failed to write TLS listener config: %s", err)
+	}
+
+	mountCerts := func(opts *dockertest.RunOptions) {
+		opts.Mounts = append(opts.Mounts,
+			dir+":/certs:ro",
+			filepath.Join(dir, "tls.conf")+":/etc/rabbitmq/conf.d/20-tls.conf:ro",
+		)
+	}
+
+	conn, _, cleanup := run(t, []func(*dockertest.RunOptions){mountCerts}, nil, defaultAMQPSPort, func(hostPort string) (*amqp.Connection, error) {
+		return amqp.DialTLS(fmt.Sprintf("amqps://%s:%s@%s/", defaultUser, defaultPassword, hostPort), tlsConfig)
+	})
+	return conn, cleanup
+}
+
+// tlsListenerConf enables the AMQPS listener using the certificates mounted at /certs.
+const tlsListenerConf = `listeners.ssl.default = 5671
+ssl_options.cacertfile = /certs/ca.pem
+ssl_options.certfile = /certs/cert.pem
+ssl_options.keyfile = /certs/key.pem
+ssl_options.verify = verify_none
+ssl_options.fail_if_no_peer_cert = false
+`
+
+// NewSelfSignedTLSConfig generates a self-signed certificate for "localhost" and returns a
+// *tls.Config that presents and trusts it, suitable for RunWithTLS.
+// The test fails immediately if the certificate cannot be generated.
+func NewSelfSignedTLSConfig(t testing.TB) *tls.Config {
+	t.Helper()
+
+	cfg, err := internal.NewSelfSignedTLSConfig()
+	if err != nil {
+		t.Fatalf("failed to generate self-signed certificate: %s", err)
+	}
+	return cfg
+}
+
+// run starts the RabbitMQ container and connects to the given container port with dial,
+// retrying until the broker accepts the connection.
+func run(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts []func(*docker.HostConfig), port string, dial func(hostPort string) (*amqp.Connection, error)) (*amqp.Connection, Endpoint, func()) {
+	t.Helper()
+
 	pool, err := dockertest.NewPool("")
 	if err != nil {
 		t.Fatalf("failed to connect to docker: %s", err)
@@ -43,8 +151,8 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		Repository: defaultRabbitMQImage,
 		Tag:        defaultRabbitMQTag,
 		Env: []string{
-			"RABBITMQ_DEFAULT_USER=guest",
-			"RABBITMQ_DEFAULT_PASS=guest",
+			"RABBITMQ_DEFAULT_USER=" + defaultUser,
+			"RABBITMQ_DEFAULT_PASS=" + defaultPassword,
 		},
 	}
 
@@ -53,36 +161,59 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		opt(defaultRunOpts)
 	}
 
+	settings := internal.TakeSettings(defaultRunOpts)
+
 	// Pass optional host configuration options
 	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
 	if err != nil {
 		t.Fatalf("failed to start rabbitmq container: %s", err)
 	}
 
-	actualPort := resource.GetHostPort(defaultAMQPPort)
+	actualPort := resource.GetHostPort(port)
 	if actualPort == "" {
 		_ = pool.Purge(resource)
 		t.Fatal("no host port was assigned for the rabbitmq container")
 	}
 	t.Logf("rabbitmq container is running on host port '%s'", actualPort)
 
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure rabbitmq container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	endpoint := Endpoint{AMQPHostPort: resource.GetHostPort(defaultAMQPPort)}
+	if mgmtPort := resource.GetHostPort(defaultMgmtPort); mgmtPort != "" {
+		endpoint.ManagementURL = "http://" + mgmtPort
+	}
+
 	// Create RabbitMQ connection
 	var conn *amqp.Connection
 
-	// Try to connect to RabbitMQ with retries
-	if err = pool.Retry(func() error {
+	// Try to connect to RabbitMQ with retries. The broker accepts connections before it has
+	// finished starting its plugins and vhosts, so the node health is checked as well when the
+	// management API is available.
+	if err = settings.Retry(pool, func() error {
 		var err error
-		conn, err = amqp.Dial(fmt.Sprintf("amqp://guest:guest@%s/", actualPort))
+		conn, err = dial(actualPort)
 		if err != nil {
 			return err
 		}
+		if endpoint.ManagementURL != "" {
+			if err := nodeHealthy(endpoint.ManagementURL); err != nil {
+				_ = conn.Close()
+				return err
+			}
+		}
 		return nil
 	}); err != nil {
+		logs.Dump()
 		_ = pool.Purge(resource)
 		t.Fatalf("could not connect to rabbitmq: %s", err)
 	}
 
 	cleanup := func() {
+		logs.Stop()
 		if err := conn.Close(); err != nil {
 			t.Logf("failed to close RabbitMQ connection: %s", err)
 		}
@@ -91,11 +222,96 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		}
 	}
 
+	return conn, endpoint, cleanup
+}
+
+// nodeHealthy checks the health of the node through the management HTTP API at mgmtURL. It succeeds
+// once every vhost is running and the node has no resource alarm in effect. These checks replace
+// the deprecated /api/healthchecks/node endpoint, which recent RabbitMQ versions no longer serve.
+func nodeHealthy(mgmtURL string) error {
+	base := strings.TrimSuffix(mgmtURL, "/")
+	for _, check := range []string{"virtual-hosts", "local-alarms"} {
+		if err := doManagementRequest(http.MethodGet, base+"/api/health/checks/"+check, ""); err != nil {
+			return fmt.Errorf("node health check %s failed: %w", check, err)
+		}
+	}
+	return nil
+}
+
+// amqpURL builds the AMQP URL for the default guest user on the given host port and vhost.
+// An empty vhost selects the default vhost ("/").
+func amqpURL(hostPort, vhost string) string {
+	return fmt.Sprintf("amqp://%s:%s@%s/%s", defaultUser, defaultPassword, hostPort, url.PathEscape(vhost))
+}
+
+// ConnectVHost dials a new connection to the given vhost on a running RabbitMQ container,
+// using the AMQP host port reported by RunWithEndpoint. It returns the connection along with
+// a cleanup function that closes it. The test fails immediately if the connection cannot be
+// established.
+func ConnectVHost(t testing.TB, actualPort, vhost string) (*amqp.Connection, func()) {
+	t.Helper()
+
+	conn, err := amqp.Dial(amqpURL(actualPort, vhost))
+	if err != nil {
+		t.Fatalf("failed to connect to vhost '%s': %s", vhost, err)
+	}
+
+	cleanup := func() {
+		if err := conn.Close(); err != nil {
+			t.Logf("failed to close RabbitMQ connection: %s", err)
+		}
+	}
+
 	return conn, cleanup
 }
 
+// PrepVHost creates a vhost with the given name through the management HTTP API at mgmtURL
+// and grants the guest user full permissions on it. The management plugin must be enabled,
+// which is the case for the default "3-management" image.
+// It returns an error if any request fails.
+func PrepVHost(t testing.TB, mgmtURL, name string) error {
+	t.Helper()
+
+	base := strings.TrimSuffix(mgmtURL, "/")
+	vhost := url.PathEscape(name)
+
+	if err := doManagementRequest(http.MethodPut, base+"/api/vhosts/"+vhost, ""); err != nil {
+		return fmt.Errorf("failed to create vhost '%s': %w", name, err)
+	}
+
+	permissions := `{"configure":".*","write":".*","read":".*"}`
+	if err := doManagementRequest(http.MethodPut, base+"/api/permissions/"+vhost+"/"+defaultUser, permissions); err != nil {
+		return fmt.Errorf("failed to grant permissions on vhost '%s': %w", name, err)
+	}
+
+	return nil
+}
+
+// doManagementRequest sends an authenticated request to the RabbitMQ management API
+// and returns an error for any non-2xx response.
+func doManagementRequest(method, target, body string) error {
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(defaultUser, defaultPassword)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s from %s %s", resp.Status, method, target)
+	}
+	return nil
+}
+
 // PrepQueue creates a queue in RabbitMQ with the specified name and options.
 // It returns the created queue and an error if the operation fails.
+// The options and queue types come from amqp091-go and replace the equivalent streadway/amqp types.
 func PrepQueue(t testing.TB, conn *amqp.Connection, name string, options amqp.Table) (*amqp.Queue, error) {
 	t.Helper()
 
@@ -105,6 +321,14 @@ func PrepQueue(t testing.TB, conn *amqp.Connection, name string, options amqp.Ta
 	}
 	defer ch.Close()
 
+	return PrepQueueWithChannel(t, ch, name, options)
+}
+
+// PrepQueueWithChannel is like PrepQueue but declares the queue on an existing channel,
+// e.g. one borrowed from a ChannelPool, instead of opening a new one.
+func PrepQueueWithChannel(t testing.TB, ch *amqp.Channel, name string, options amqp.Table) (*amqp.Queue, error) {
+	t.Helper()
+
 	durable := false
 	autoDelete := false
 	exclusive := false
@@ -159,6 +383,14 @@ func PrepExchange(t testing.TB, conn *amqp.Connection, name string, kind string,
 	}
 	defer ch.Close()
 
+	return PrepExchangeWithChannel(t, ch, name, kind, options)
+}
+
+// PrepExchangeWithChannel is like PrepExchange but declares the exchange on an existing channel,
+// e.g. one borrowed from a ChannelPool, instead of opening a new one.
+func PrepExchangeWithChannel(t testing.TB, ch *amqp.Channel, name string, kind string, options amqp.Table) error {
+	t.Helper()
+
 	durable := false
 	autoDelete := false
 	internal := false
@@ -187,7 +419,7 @@ func PrepExchange(t testing.TB, conn *amqp.Connection, name string, kind string,
 		}
 	}
 
-	err = ch.ExchangeDeclare(
+	err := ch.ExchangeDeclare(
 		name,
 		kind,
 		durable,
@@ -214,7 +446,15 @@ func PrepBinding(t testing.TB, conn *amqp.Connection, queueName string, exchange
 	}
 	defer ch.Close()
 
-	err = ch.QueueBind(
+	return PrepBindingWithChannel(t, ch, queueName, exchangeName, routingKey, options)
+}
+
+// PrepBindingWithChannel is like PrepBinding but creates the binding on an existing channel,
+// e.g. one borrowed from a ChannelPool, instead of opening a new one.
+func PrepBindingWithChannel(t testing.TB, ch *amqp.Channel, queueName string, exchangeName string, routingKey string, options amqp.Table) error {
+	t.Helper()
+
+	err := ch.QueueBind(
 		queueName,
 		routingKey,
 		exchangeName,
@@ -230,6 +470,7 @@ func PrepBinding(t testing.TB, conn *amqp.Connection, queueName string, exchange
 
 // PublishMessage publishes a message to the specified exchange with the routing key and options.
 // It returns an error if the operation fails.
+// The options parameter is an amqp091-go amqp.Publishing, a drop-in for the streadway/amqp type.
 func PublishMessage(t testing.TB, conn *amqp.Connection, exchange string, routingKey string, message []byte, options amqp.Publishing) error {
 	t.Helper()
 
@@ -239,6 +480,14 @@ func PublishMessage(t testing.TB, conn *amqp.Connection, exchange string, routin
 	}
 	defer ch.Close()
 
+	return PublishMessageWithChannel(t, ch, exchange, routingKey, message, options)
+}
+
+// PublishMessageWithChannel is like PublishMessage but publishes on an existing channel,
+// e.g. one borrowed from a ChannelPool, instead of opening a new one.
+func PublishMessageWithChannel(t testing.TB, ch *amqp.Channel, exchange string, routingKey string, message []byte, options amqp.Publishing) error {
+	t.Helper()
+
 	// Set default content type if not provided
 	if options.ContentType == "" {
 		options.ContentType = "text/plain"
@@ -247,7 +496,7 @@ func PublishMessage(t testing.TB, conn *amqp.Connection, exchange string, routin
 	// Set the message body
 	options.Body = message
 
-	err = ch.Publish(
+	err := ch.Publish(
 		exchange,
 		routingKey,
 		false, // mandatory
@@ -263,6 +512,7 @@ func PublishMessage(t testing.TB, conn *amqp.Connection, exchange string, routin
 
 // ConsumeMessages sets up a consumer for a queue and returns a channel for receiving messages.
 // It also returns a function to cancel the consumer.
+// Deliveries are amqp091-go amqp.Delivery values, a drop-in for the streadway/amqp type.
 func ConsumeMessages(t testing.TB, conn *amqp.Connection, queueName string) (<-chan amqp.Delivery, func(), error) {
 	t.Helper()
 
@@ -297,3 +547,196 @@ func ConsumeMessages(t testing.TB, conn *amqp.Connection, queueName string) (<-c
 
 	return deliveries, cleanup, nil
 }
+
+// ConsumeJSON is like ConsumeMessages but decodes the JSON body of each delivery into a T.
+// A delivery is acknowledged once it has been decoded and sent on the returned value channel.
+// A delivery that cannot be decoded is rejected without requeueing, so it goes to the queue's
+// dead-letter exchange if one is configured, and the decoding error is sent on the error channel.
+// The error channel is buffered, and an error that does not fit is logged with t.Logf instead, so a
+// caller that only ranges over the value channel never stalls the consumer; receive from the error
+// channel as well to assert on malformed messages. Both channels are closed by the returned cleanup
+// function, which also cancels the consumer.
+func ConsumeJSON[T any](t testing.TB, conn *amqp.Connection, queueName string) (<-chan T, <-chan error, func(), error) {
+	t.Helper()
+
+	deliveries, cancel, err := ConsumeMessages(t, conn, queueName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	values := make(chan T)
+	errs := make(chan error, 100)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer close(errs)
+		defer close(values)
+		for d := range deliveries {
+			var v T
+			if err := json.Unmarshal(d.Body, &v); err != nil {
+				_ = d.Nack(false, false)
+				err = fmt.Errorf("failed to decode message %d from queue '%s': %w", d.DeliveryTag, queueName, err)
+				select {
+				case errs <- err:
+				default:
+					t.Logf("%s (error channel full)", err)
+				}
+				continue
+			}
+			select {
+			case values <- v:
+				_ = d.Ack(false)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Messages that were delivered but not yet received are requeued when the channel closes.
+	cleanup := func() {
+		close(done)
+		cancel()
+		<-finished
+	}
+
+	return values, errs, cleanup, nil
+}
+
+// ExpectMessage waits up to within for a delivery on deliveries and returns it. The test fails
+// immediately if no delivery arrives in time or the channel is closed. The delivery is returned
+// unacknowledged.
+func ExpectMessage(t testing.TB, deliveries <-chan amqp.Delivery, within time.Duration) amqp.Delivery {
+	t.Helper()
+
+	select {
+	case d, ok := <-deliveries:
+		if !ok {
+			t.Fatal("expected a message, but the delivery channel was closed")
+		}
+		return d
+	case <-time.After(within):
+		t.Fatalf("expected a message within %s, but none arrived", within)
+	}
+	return amqp.Delivery{}
+}
+
+// CollectMessages receives n deliveries from deliveries within timeout, acknowledges each of them,
+// and returns their bodies in the order they arrived. Compare the result regardless of order when
+// the producer does not guarantee one. The test fails immediately if fewer than n deliveries arrive
+// in time, the channel is closed, or a delivery cannot be acknowledged; deliveries must therefore
+// come from a consumer without auto-ack, such as ConsumeMessages.
+func CollectMessages(t testing.TB, deliveries <-chan amqp.Delivery, n int, timeout time.Duration) [][]byte {
+	t.Helper()
+
+	deadline := time.After(timeout)
+	bodies := make([][]byte, 0, n)
+	for len(bodies) < n {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				t.Fatalf("expected %d messages, but the delivery channel was closed after %d", n, len(bodies))
+			}
+			if err := d.Ack(false); err != nil {
+				t.Fatalf("failed to acknowledge message %d: %s", len(bodies), err)
+			}
+			bodies = append(bodies, d.Body)
+		case <-deadline:
+			t.Fatalf("expected %d messages within %s, but only %d arrived", n, timeout, len(bodies))
+		}
+	}
+	return bodies
+}
+
+// ExpectNoMessage waits for within and fails the test immediately if a delivery arrives on
+// deliveries in the meantime, e.g. to assert that a message was not routed to a queue.
+// A closed delivery channel cannot deliver anything, so it ends the wait successfully.
+func ExpectNoMessage(t testing.TB, deliveries <-chan amqp.Delivery, within time.Duration) {
+	t.Helper()
+
+	select {
+	case d, ok := <-deliveries:
+		if ok {
+			t.Fatalf("expected no message within %s, but received %q (routing key '%s')", within, d.Body, d.RoutingKey)
+		}
+	case <-time.After(within):
+	}
+}
+
+// ChannelPool is a fixed-size pool of AMQP channels opened on a single connection.
+// Reusing channels avoids the round trips of opening and closing a channel for every
+// operation, which adds up in message-heavy tests. A ChannelPool is safe for concurrent use.
+type ChannelPool struct {
+	conn     *amqp.Connection
+	channels chan *amqp.Channel
+}
+
+// NewChannelPool opens size channels on conn and returns them as a ChannelPool along with a
+// cleanup function that closes every pooled channel. The test fails immediately if a channel
+// cannot be opened.
+func NewChannelPool(t testing.TB, conn *amqp.Connection, size int) (*ChannelPool, func()) {
+	t.Helper()
+
+	if size <= 0 {
+		t.Fatalf("channel pool size must be positive, got %d", size)
+	}
+
+	p := &ChannelPool{
+		conn:     conn,
+		channels: make(chan *amqp.Channel, size),
+	}
+	for i := 0; i < size; i++ {
+		ch, err := conn.Channel()
+		if err != nil {
+			p.close(t)
+			t.Fatalf("failed to open a channel: %s", err)
+		}
+		p.channels <- ch
+	}
+
+	cleanup := func() {
+		p.close(t)
+	}
+
+	return p, cleanup
+}
+
+// Get borrows a channel from the pool, blocking until one is available.
+// A channel that was closed by the broker (e.g. after a channel-level error) is
+// transparently replaced by a newly opened one.
+func (p *ChannelPool) Get() (*amqp.Channel, error) {
+	ch := <-p.channels
+	if !ch.IsClosed() {
+		return ch, nil
+	}
+
+	fresh, err := p.conn.Channel()
+	if err != nil {
+		// Put the closed channel back so the pool keeps its size; it is reopened on the next Get.
+		p.channels <- ch
+		return nil, fmt.Errorf("failed to reopen a channel: %w", err)
+	}
+	return fresh, nil
+}
+
+// Put returns a channel borrowed with Get to the pool.
+func (p *ChannelPool) Put(ch *amqp.Channel) {
+	p.channels <- ch
+}
+
+// close closes every channel currently held by the pool.
+func (p *ChannelPool) close(t testing.TB) {
+	for {
+		select {
+		case ch := <-p.channels:
+			if ch.IsClosed() {
+				continue
+			}
+			if err := ch.Close(); err != nil {
+				t.Logf("failed to close channel: %s", err)
+			}
+		default:
+			return
+		}
+	}
+}
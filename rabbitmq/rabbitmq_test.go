@@ -1,12 +1,15 @@
 package rabbitmq_test
 
 import (
+	"fmt"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
-	"github.com/streadway/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
 	rabbitmqtest "github.com/vvatanabe/dockertestx/rabbitmq"
 )
 
@@ -240,3 +243,317 @@ func TestRabbitMQPublishConsume(t *testing.T) {
 		t.Fatal("timed out waiting for message")
 	}
 }
+
+// TestRabbitMQVHost tests creating a vhost and confirming it is isolated from the default vhost.
+func TestRabbitMQVHost(t *testing.T) {
+	// Start a RabbitMQ container and keep its endpoint for further connections
+	_, endpoint, cleanup := rabbitmqtest.RunWithEndpoint(t, nil)
+	defer cleanup()
+
+	// Create a vhost through the management API
+	vhost := "tenant-a"
+	if err := rabbitmqtest.PrepVHost(t, endpoint.ManagementURL, vhost); err != nil {
+		t.Fatalf("failed to create vhost: %v", err)
+	}
+
+	// Connect to the new vhost and declare a queue in it
+	vhostConn, vhostCleanup := rabbitmqtest.ConnectVHost(t, endpoint.AMQPHostPort, vhost)
+	defer vhostCleanup()
+
+	queueName := "tenant-queue"
+	if _, err := rabbitmqtest.PrepQueue(t, vhostConn, queueName, nil); err != nil {
+		t.Fatalf("failed to create queue in vhost: %v", err)
+	}
+
+	// The queue must not be visible from the default vhost
+	defaultConn, defaultCleanup := rabbitmqtest.ConnectVHost(t, endpoint.AMQPHostPort, "/")
+	defer defaultCleanup()
+
+	ch, err := defaultConn.Channel()
+	if err != nil {
+		t.Fatalf("failed to open a channel: %v", err)
+	}
+	defer ch.Close()
+
+	_, err = ch.QueueDeclarePassive(queueName, false, false, false, false, nil)
+	if err == nil {
+		t.Fatalf("expected queue '%s' to be isolated from the default vhost", queueName)
+	}
+
+	t.Log("Successfully verified vhost isolation")
+}
+
+// TestRabbitMQWithTLS tests connecting over the AMQPS listener with a self-signed certificate.
+func TestRabbitMQWithTLS(t *testing.T) {
+	// Start a RabbitMQ container with TLS; passing nil generates a self-signed certificate
+	conn, cleanup := rabbitmqtest.RunWithTLS(t, nil)
+	defer cleanup()
+
+	// Test creating a channel over the TLS connection
+	ch, err := conn.Channel()
+	if err != nil {
+		t.Fatalf("failed to open a channel: %v", err)
+	}
+	defer ch.Close()
+
+	t.Log("Successfully connected to RabbitMQ over TLS and created a channel")
+}
+
+// TestRabbitMQChannelPool tests publishing many messages with pooled channels and compares
+// the timing with the per-call path that opens a channel for every message.
+func TestRabbitMQChannelPool(t *testing.T) {
+	conn, cleanup := rabbitmqtest.Run(t)
+	defer cleanup()
+
+	const messageCount = 1000
+
+	pool, poolCleanup := rabbitmqtest.NewChannelPool(t, conn, 4)
+	defer poolCleanup()
+
+	// Declare the queue with a pooled channel
+	ch, err := pool.Get()
+	if err != nil {
+		t.Fatalf("failed to get a channel from the pool: %v", err)
+	}
+	queueName := "test-queue-pool"
+	if _, err := rabbitmqtest.PrepQueueWithChannel(t, ch, queueName, nil); err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	pool.Put(ch)
+
+	// Publish using pooled channels
+	start := time.Now()
+	for i := 0; i < messageCount; i++ {
+		ch, err := pool.Get()
+		if err != nil {
+			t.Fatalf("failed to get a channel from the pool: %v", err)
+		}
+		if err := rabbitmqtest.PublishMessageWithChannel(t, ch, "", queueName, []byte("pooled"), amqp.Publishing{}); err != nil {
+			t.Fatalf("failed to publish message %d: %v", i, err)
+		}
+		pool.Put(ch)
+	}
+	pooled := time.Since(start)
+
+	// Publish using a new channel per call
+	start = time.Now()
+	for i := 0; i < messageCount; i++ {
+		if err := rabbitmqtest.PublishMessage(t, conn, "", queueName, []byte("per-call"), amqp.Publishing{}); err != nil {
+			t.Fatalf("failed to publish message %d: %v", i, err)
+		}
+	}
+	perCall := time.Since(start)
+
+	t.Logf("published %d messages: pooled=%s per-call=%s", messageCount, pooled, perCall)
+
+	// Verify every message reached the queue
+	ch, err = pool.Get()
+	if err != nil {
+		t.Fatalf("failed to get a channel from the pool: %v", err)
+	}
+	defer pool.Put(ch)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		q, err := ch.QueueDeclarePassive(queueName, false, false, false, false, nil)
+		if err != nil {
+			t.Fatalf("failed to inspect queue: %v", err)
+		}
+		if q.Messages == 2*messageCount {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d messages in queue, got %d", 2*messageCount, q.Messages)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TestRabbitMQConsumeJSON tests decoding JSON messages into structs and surfacing malformed ones.
+func TestRabbitMQConsumeJSON(t *testing.T) {
+	conn, cleanup := rabbitmqtest.Run(t)
+	defer cleanup()
+
+	queueName := "test-queue-json"
+	if _, err := rabbitmqtest.PrepQueue(t, conn, queueName, nil); err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	type Order struct {
+		ID    int    `json:"id"`
+		Item  string `json:"item"`
+		Count int    `json:"count"`
+	}
+	orders, errs, consumerCleanup, err := rabbitmqtest.ConsumeJSON[Order](t, conn, queueName)
+	if err != nil {
+		t.Fatalf("failed to set up consumer: %v", err)
+	}
+	defer consumerCleanup()
+
+	publishOptions := amqp.Publishing{ContentType: "application/json"}
+	for _, body := range []string{
+		`{"id": 1, "item": "apple", "count": 3}`,
+		`{"id": 2, "item": `,
+		`{"id": 3, "item": "banana", "count": 5}`,
+	} {
+		if err := rabbitmqtest.PublishMessage(t, conn, "", queueName, []byte(body), publishOptions); err != nil {
+			t.Fatalf("failed to publish message: %v", err)
+		}
+	}
+
+	var got []Order
+	var decodeErrs []error
+	timeout := time.After(10 * time.Second)
+	for len(got) < 2 || len(decodeErrs) < 1 {
+		select {
+		case o := <-orders:
+			got = append(got, o)
+		case err := <-errs:
+			decodeErrs = append(decodeErrs, err)
+		case <-timeout:
+			t.Fatalf("timed out waiting for messages: got %v and errors %v", got, decodeErrs)
+		}
+	}
+
+	want := []Order{{ID: 1, Item: "apple", Count: 3}, {ID: 3, Item: "banana", Count: 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected orders %v, got %v", want, got)
+	}
+	if len(decodeErrs) != 1 {
+		t.Errorf("expected 1 decoding error, got %v", decodeErrs)
+	}
+}
+
+// TestRabbitMQConsumeJSONValuesOnly checks that a malformed message does not block the values
+// published after it when the caller never reads the error channel.
+func TestRabbitMQConsumeJSONValuesOnly(t *testing.T) {
+	conn, cleanup := rabbitmqtest.Run(t)
+	defer cleanup()
+
+	queueName := "test-queue-json-values"
+	if _, err := rabbitmqtest.PrepQueue(t, conn, queueName, nil); err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+
+	type Order struct {
+		ID int `json:"id"`
+	}
+	orders, _, consumerCleanup, err := rabbitmqtest.ConsumeJSON[Order](t, conn, queueName)
+	if err != nil {
+		t.Fatalf("failed to set up consumer: %v", err)
+	}
+	defer consumerCleanup()
+
+	for _, body := range []string{`not json`, `{"id": 1}`, `{"id": 2}`} {
+		if err := rabbitmqtest.PublishMessage(t, conn, "", queueName, []byte(body), amqp.Publishing{}); err != nil {
+			t.Fatalf("failed to publish message: %v", err)
+		}
+	}
+
+	timeout := time.After(10 * time.Second)
+	for _, want := range []int{1, 2} {
+		select {
+		case o := <-orders:
+			if o.ID != want {
+				t.Errorf("expected order %d, got %d", want, o.ID)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for order %d", want)
+		}
+	}
+}
+
+// TestRabbitMQExpectMessage tests that only messages matching the binding's routing key are delivered.
+func TestRabbitMQExpectMessage(t *testing.T) {
+	conn, cleanup := rabbitmqtest.Run(t)
+	defer cleanup()
+
+	queueName := "test-queue-routing"
+	exchangeName := "test-exchange-routing"
+	if _, err := rabbitmqtest.PrepQueue(t, conn, queueName, nil); err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	if err := rabbitmqtest.PrepExchange(t, conn, exchangeName, "direct", nil); err != nil {
+		t.Fatalf("failed to create exchange: %v", err)
+	}
+	if err := rabbitmqtest.PrepBinding(t, conn, queueName, exchangeName, "orders.created", nil); err != nil {
+		t.Fatalf("failed to create binding: %v", err)
+	}
+
+	deliveries, consumerCleanup, err := rabbitmqtest.ConsumeMessages(t, conn, queueName)
+	if err != nil {
+		t.Fatalf("failed to set up consumer: %v", err)
+	}
+	defer consumerCleanup()
+
+	// A routing key that does not match the binding is not routed to the queue
+	if err := rabbitmqtest.PublishMessage(t, conn, exchangeName, "orders.deleted", []byte("filtered"), amqp.Publishing{}); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+	rabbitmqtest.ExpectNoMessage(t, deliveries, time.Second)
+
+	if err := rabbitmqtest.PublishMessage(t, conn, exchangeName, "orders.created", []byte("routed"), amqp.Publishing{}); err != nil {
+		t.Fatalf("failed to publish message: %v", err)
+	}
+	delivery := rabbitmqtest.ExpectMessage(t, deliveries, 5*time.Second)
+	if string(delivery.Body) != "routed" {
+		t.Errorf("expected message 'routed', got '%s'", delivery.Body)
+	}
+	if err := delivery.Ack(false); err != nil {
+		t.Errorf("failed to acknowledge message: %v", err)
+	}
+}
+
+// TestRabbitMQReadyForDeclares starts RabbitMQ several times and declares a queue and an exchange
+// right after Run returns, which failed intermittently when only the AMQP dial was awaited.
+func TestRabbitMQReadyForDeclares(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		t.Run(fmt.Sprintf("run-%d", i), func(t *testing.T) {
+			conn, cleanup := rabbitmqtest.Run(t)
+			defer cleanup()
+
+			if _, err := rabbitmqtest.PrepQueue(t, conn, "ready-queue", amqp.Table{"durable": true}); err != nil {
+				t.Fatalf("failed to declare queue right after Run: %v", err)
+			}
+			if err := rabbitmqtest.PrepExchange(t, conn, "ready-exchange", "direct", nil); err != nil {
+				t.Fatalf("failed to declare exchange right after Run: %v", err)
+			}
+		})
+	}
+}
+
+// TestRabbitMQCollectMessages publishes five messages and collects them regardless of order.
+func TestRabbitMQCollectMessages(t *testing.T) {
+	conn, cleanup := rabbitmqtest.Run(t)
+	defer cleanup()
+
+	queueName := "test-queue-collect"
+	if _, err := rabbitmqtest.PrepQueue(t, conn, queueName, nil); err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	deliveries, consumerCleanup, err := rabbitmqtest.ConsumeMessages(t, conn, queueName)
+	if err != nil {
+		t.Fatalf("failed to set up consumer: %v", err)
+	}
+	defer consumerCleanup()
+
+	want := []string{"m1", "m2", "m3", "m4", "m5"}
+	for _, body := range want {
+		if err := rabbitmqtest.PublishMessage(t, conn, "", queueName, []byte(body), amqp.Publishing{}); err != nil {
+			t.Fatalf("failed to publish message: %v", err)
+		}
+	}
+
+	bodies := rabbitmqtest.CollectMessages(t, deliveries, len(want), 5*time.Second)
+	got := make([]string, 0, len(bodies))
+	for _, b := range bodies {
+		got = append(got, string(b))
+	}
+	slices.Sort(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected messages %v, got %v", want, got)
+	}
+
+	// All messages were acknowledged, so nothing is redelivered
+	rabbitmqtest.ExpectNoMessage(t, deliveries, time.Second)
+}
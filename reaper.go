@@ -0,0 +1,134 @@
+package dockertestx
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	defaultReaperImage = "testcontainers/ryuk"
+	defaultReaperTag   = "0.11.0"
+	defaultReaperPort  = "8080/tcp"
+	// SessionLabel is the label that EnableReaper adds to every container of the test process.
+	SessionLabel = "dockertestx.session"
+)
+
+// reaper holds the connection to the ryuk sidecar. Ryuk removes the containers of the session
+// once this connection has been closed for longer than its reconnection timeout (10s by default).
+var reaper struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// EnableReaper starts a testcontainers/ryuk sidecar that removes every container started afterwards
+// once the test process exits, including when it crashes, panics or is killed before the cleanup
+// functions run. It is opt-in and typically called at the top of a test; calls after the first one
+// do nothing.
+//
+// Every container started by this module after the call, through any package, carries the
+// SessionLabel label with an ID unique to the process. Ryuk needs access to the Docker socket at
+// /var/run/docker.sock on the Docker host. If the sidecar cannot be started, the test fails.
+func EnableReaper(t testing.TB) {
+	t.Helper()
+
+	if err := TryEnableReaper(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TryEnableReaper is like EnableReaper, but it returns an error instead of failing the test,
+// so that it can be called from TestMain.
+func TryEnableReaper() error {
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+	if reaper.conn != nil {
+		return nil
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return fmt.Errorf("failed to connect to docker: %w", err)
+	}
+
+	// Ryuk removes itself after pruning, and it must not be labeled as part of the session.
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   defaultReaperImage,
+		Tag:          defaultReaperTag,
+		Mounts:       []string{"/var/run/docker.sock:/var/run/docker.sock"},
+		ExposedPorts: []string{defaultReaperPort},
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start reaper container: %w", err)
+	}
+
+	hostPort := resource.GetHostPort(defaultReaperPort)
+	if hostPort == "" {
+		_ = pool.Purge(resource)
+		return fmt.Errorf("no host port was assigned for the reaper container")
+	}
+
+	session, err := newSessionID()
+	if err != nil {
+		_ = pool.Purge(resource)
+		return fmt.Errorf("failed to create reaper session ID: %w", err)
+	}
+
+	var conn net.Conn
+	if err := pool.Retry(func() error {
+		conn, err = registerSession(hostPort, session)
+		return err
+	}); err != nil {
+		_ = pool.Purge(resource)
+		return fmt.Errorf("could not connect to reaper: %w", err)
+	}
+
+	reaper.conn = conn
+	internal.SetSessionLabel(SessionLabel, session)
+	return nil
+}
+
+// registerSession connects to ryuk at hostPort and asks it to reap the containers labeled with session.
+// The returned connection must stay open for as long as the containers should be kept.
+func registerSession(hostPort, session string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", hostPort, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := fmt.Fprintf(conn, "label=%s=%s\n", SessionLabel, session); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	ack, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if strings.TrimSpace(ack) != "ACK" {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected reply from reaper: %q", ack)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// newSessionID returns a random ID for the session label.
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -2,10 +2,14 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/redis/go-redis/v9"
+	"github.com/vvatanabe/dockertestx/internal"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -13,6 +17,9 @@ import (
 const (
 	defaultRedisImage = "redis"
 	defaultRedisTag   = "7.2"
+	defaultRedisPort  = "6379/tcp"
+	// defaultRedisTLSPort is the port RunWithTLS serves TLS connections on.
+	defaultRedisTLSPort = "6380/tcp"
 )
 
 // Run starts a Redis Docker container using the default settings and returns a connected
@@ -32,6 +39,72 @@ func Run(t testing.TB) (*redis.Client, func()) {
 func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*redis.Client, func()) {
 	t.Helper()
 
+	return run(t, runOpts, hostOpts, defaultRedisPort, func(addr string) *redis.Client {
+		return redis.NewClient(&redis.Options{
+			Addr: addr,
+		})
+	})
+}
+
+// RunWithTLS starts a Redis Docker container that only accepts TLS connections on 6380/tcp, and
+// returns a *redis.Client connected with Options.TLSConfig, along with a cleanup function.
+//
+// The first certificate of tlsConfig is mounted into the container as the server certificate,
+// and tlsConfig itself is used as the client configuration, so it should also trust that
+// certificate (e.g. via RootCAs). Clients are not asked for a certificate. If tlsConfig is nil,
+// a self-signed configuration is generated with NewSelfSignedTLSConfig.
+func RunWithTLS(t testing.TB, tlsConfig *tls.Config) (*redis.Client, func()) {
+	t.Helper()
+
+	if tlsConfig == nil {
+		tlsConfig = NewSelfSignedTLSConfig(t)
+	}
+
+	dir := t.TempDir()
+	if _, _, _, err := internal.WriteTLSFiles(dir, tlsConfig); err != nil {
+		t.Fatalf("failed to write TLS files: %s", err)
+	}
+
+	enableTLS := func(opts *dockertest.RunOptions) {
+		opts.Mounts = append(opts.Mounts, dir+":/certs:ro")
+		opts.ExposedPorts = append(opts.ExposedPorts, defaultRedisTLSPort)
+		opts.Cmd = []string{
+			"redis-server",
+			"--port", "0",
+			"--tls-port", "6380",
+			"--tls-cert-file", "/certs/cert.pem",
+			"--tls-key-file", "/certs/key.pem",
+			"--tls-ca-cert-file", "/certs/ca.pem",
+			"--tls-auth-clients", "no",
+		}
+	}
+
+	return run(t, []func(*dockertest.RunOptions){enableTLS}, nil, defaultRedisTLSPort, func(addr string) *redis.Client {
+		return redis.NewClient(&redis.Options{
+			Addr:      addr,
+			TLSConfig: tlsConfig,
+		})
+	})
+}
+
+// NewSelfSignedTLSConfig generates a self-signed certificate for "localhost" and returns a
+// *tls.Config that presents and trusts it, suitable for RunWithTLS.
+// The test fails immediately if the certificate cannot be generated.
+func NewSelfSignedTLSConfig(t testing.TB) *tls.Config {
+	t.Helper()
+
+	cfg, err := internal.NewSelfSignedTLSConfig()
+	if err != nil {
+		t.Fatalf("failed to generate self-signed certificate: %s", err)
+	}
+	return cfg
+}
+
+// run starts the Redis container and connects to the given container port with a client created
+// by newClient, retrying until the server answers a PING.
+func run(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts []func(*docker.HostConfig), port string, newClient func(addr string) *redis.Client) (*redis.Client, func()) {
+	t.Helper()
+
 	pool, err := dockertest.NewPool("")
 	if err != nil {
 		t.Fatalf("failed to connect to docker: %s", err)
@@ -48,36 +121,45 @@ func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOp
 		opt(defaultRunOpts)
 	}
 
+	settings := internal.TakeSettings(defaultRunOpts)
+
 	// Pass optional host configuration options
 	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
 	if err != nil {
 		t.Fatalf("failed to start redis container: %s", err)
 	}
 
-	actualPort := resource.GetHostPort("6379/tcp")
-	if actualPort == "" {
+	host, port, err := internal.HostAddress(pool, resource, port)
+	if err != nil {
 		_ = pool.Purge(resource)
-		t.Fatal("no host port was assigned for the redis container")
+		t.Fatalf("failed to get the address of the redis container: %s", err)
 	}
+	actualPort := net.JoinHostPort(host, port)
 	t.Logf("redis container is running on host port '%s'", actualPort)
 
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure redis container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
 	// Create Redis client
 	var client *redis.Client
 
 	// Try to connect to Redis with retries
 	ctx := context.Background()
-	if err = pool.Retry(func() error {
-		client = redis.NewClient(&redis.Options{
-			Addr: actualPort,
-		})
+	if err = settings.Retry(pool, func() error {
+		client = newClient(actualPort)
 		// Ping the server to check if it's responsive
 		return client.Ping(ctx).Err()
 	}); err != nil {
+		logs.Dump()
 		_ = pool.Purge(resource)
 		t.Fatalf("could not connect to redis: %s", err)
 	}
 
 	cleanup := func() {
+		logs.Stop()
 		if err := client.Close(); err != nil {
 			t.Logf("failed to close Redis client: %s", err)
 		}
@@ -104,6 +186,34 @@ func PrepRedis(t testing.TB, client *redis.Client, items map[string]interface{},
 	return nil
 }
 
+// PrepRedisPipeline is like PrepRedis, but it sends all SET commands in a single pipeline,
+// which saves a round trip per key when seeding many items.
+// The error of every command is checked; if any fails, it returns an error naming the first failing key.
+func PrepRedisPipeline(t testing.TB, client *redis.Client, items map[string]interface{}, expiration time.Duration) error {
+	t.Helper()
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range items {
+			pipe.Set(ctx, key, value, expiration)
+		}
+		return nil
+	})
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			return fmt.Errorf("failed to set item with key '%v': %w", cmd.Args()[1], err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to execute pipeline: %w", err)
+	}
+	return nil
+}
+
 // PrepRedisList sets up test data in a Redis list.
 // It accepts a key and a list of values to be stored.
 // If any operation fails, it returns an error.
@@ -157,3 +267,106 @@ func PrepRedisSortedSet(t testing.TB, client *redis.Client, key string, members
 	}
 	return nil
 }
+
+// PrepRedisACLUser creates or updates the ACL user username with the given password and rules
+// (Redis 6 or later), e.g. []string{"~*", "+@read"} for a read-only user. The user is enabled,
+// and its rules are appended to any it already has. If the command fails, it returns an error.
+func PrepRedisACLUser(t testing.TB, client *redis.Client, username, password string, rules []string) error {
+	t.Helper()
+
+	args := []interface{}{"ACL", "SETUSER", username, "on", ">" + password}
+	for _, rule := range rules {
+		args = append(args, rule)
+	}
+	if err := client.Do(context.Background(), args...).Err(); err != nil {
+		return fmt.Errorf("failed to set ACL user '%s': %w", username, err)
+	}
+	return nil
+}
+
+// NewRedisAsUser returns a client for the Redis server at actualPort ("host:port", e.g.
+// client.Options().Addr) that authenticates as the given ACL user. Commands the user is not
+// allowed to run fail with a NOPERM error. The caller is responsible for closing the client.
+func NewRedisAsUser(t testing.TB, actualPort, username, password string) *redis.Client {
+	t.Helper()
+
+	return redis.NewClient(&redis.Options{
+		Addr:     actualPort,
+		Username: username,
+		Password: password,
+	})
+}
+
+// KeyEvent describes a keyspace notification received by EnableKeyspaceNotifications.
+type KeyEvent struct {
+	// Event is the name of the event, e.g. "expired", "set" or "del".
+	Event string
+	// Key is the key the event occurred on.
+	Key string
+}
+
+// EnableKeyspaceNotifications turns on keyspace notifications for key events with
+// CONFIG SET notify-keyspace-events, subscribes to the __keyevent@<db>__ channels of the given
+// events (e.g. "expired", "set", "del") in the client's database, and returns a channel that
+// receives them along with a cleanup function that ends the subscription and closes the channel.
+// Without events, every key event is delivered. Notifications stay enabled on the server after
+// cleanup. Events that happen after the function returns are delivered; the channel buffers up to
+// 100 events that have not been received yet. The test fails immediately if notifications cannot be
+// enabled or the subscription cannot be established.
+//
+// Note that Redis reports "expired" when it notices that a key has expired, either on access or in
+// its background cycle, which can lag the TTL by up to about a hundred milliseconds.
+func EnableKeyspaceNotifications(t testing.TB, client *redis.Client, events ...string) (<-chan KeyEvent, func()) {
+	t.Helper()
+
+	ctx := context.Background()
+	// "E" enables the __keyevent@<db>__ channels and "A" all event classes.
+	if err := client.ConfigSet(ctx, "notify-keyspace-events", "EA").Err(); err != nil {
+		t.Fatalf("failed to enable keyspace notifications: %s", err)
+	}
+
+	prefix := fmt.Sprintf("__keyevent@%d__:", client.Options().DB)
+	var pubsub *redis.PubSub
+	if len(events) == 0 {
+		pubsub = client.PSubscribe(ctx, prefix+"*")
+	} else {
+		channels := make([]string, 0, len(events))
+		for _, event := range events {
+			channels = append(channels, prefix+event)
+		}
+		pubsub = client.Subscribe(ctx, channels...)
+	}
+	// Wait for the subscription to be confirmed so that no event is missed.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		t.Fatalf("failed to subscribe to keyspace notifications: %s", err)
+	}
+
+	keyEvents := make(chan KeyEvent, 100)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(keyEvents)
+		for msg := range pubsub.Channel() {
+			select {
+			case keyEvents <- KeyEvent{
+				Event: strings.TrimPrefix(msg.Channel, prefix),
+				Key:   msg.Payload,
+			}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	cleanup := func() {
+		close(stop)
+		if err := pubsub.Close(); err != nil {
+			t.Logf("failed to close keyspace notification subscription: %s", err)
+		}
+		<-done
+	}
+
+	return keyEvents, cleanup
+}
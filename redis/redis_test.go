@@ -6,6 +6,8 @@ import (
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/redis/go-redis/v9"
 	redistest "github.com/vvatanabe/dockertestx/redis"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -280,3 +282,139 @@ func TestRedisDataTypes(t *testing.T) {
 		}
 	})
 }
+
+// TestPrepRedisPipeline demonstrates seeding many keys in a single pipeline.
+func TestPrepRedisPipeline(t *testing.T) {
+	client, cleanup := redistest.Run(t)
+	defer cleanup()
+
+	items := seedItems(100)
+	if err := redistest.PrepRedisPipeline(t, client, items, time.Hour); err != nil {
+		t.Fatalf("PrepRedisPipeline failed: %v", err)
+	}
+
+	ctx := context.Background()
+	n, err := client.DBSize(ctx).Result()
+	if err != nil {
+		t.Fatalf("failed to count keys: %v", err)
+	}
+	if n != int64(len(items)) {
+		t.Errorf("expected %d keys, but got %d", len(items), n)
+	}
+	got, err := client.Get(ctx, "key-42").Result()
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if got != "value-42" {
+		t.Errorf("expected value 'value-42', but got '%s'", got)
+	}
+}
+
+// BenchmarkPrepRedis compares seeding with individual SET calls to seeding with a pipeline.
+func BenchmarkPrepRedis(b *testing.B) {
+	client, cleanup := redistest.Run(b)
+	defer cleanup()
+
+	items := seedItems(1000)
+	b.Run("Loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := redistest.PrepRedis(b, client, items, time.Hour); err != nil {
+				b.Fatalf("PrepRedis failed: %v", err)
+			}
+		}
+	})
+	b.Run("Pipeline", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := redistest.PrepRedisPipeline(b, client, items, time.Hour); err != nil {
+				b.Fatalf("PrepRedisPipeline failed: %v", err)
+			}
+		}
+	})
+}
+
+// seedItems returns n distinct key-value pairs.
+func seedItems(n int) map[string]interface{} {
+	items := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		items["key-"+strconv.Itoa(i)] = "value-" + strconv.Itoa(i)
+	}
+	return items
+}
+
+// TestPrepRedisACLUser demonstrates a read-only user whose writes are rejected.
+func TestPrepRedisACLUser(t *testing.T) {
+	client, cleanup := redistest.Run(t)
+	defer cleanup()
+
+	if err := redistest.PrepRedis(t, client, map[string]interface{}{"greeting": "hello"}, 0); err != nil {
+		t.Fatalf("PrepRedis failed: %v", err)
+	}
+	if err := redistest.PrepRedisACLUser(t, client, "reader", "secret", []string{"~*", "+@read", "+@connection"}); err != nil {
+		t.Fatalf("PrepRedisACLUser failed: %v", err)
+	}
+
+	reader := redistest.NewRedisAsUser(t, client.Options().Addr, "reader", "secret")
+	defer reader.Close()
+
+	ctx := context.Background()
+	got, err := reader.Get(ctx, "greeting").Result()
+	if err != nil {
+		t.Fatalf("expected the read-only user to read, but got: %v", err)
+	}
+	if got != "hello" {
+		t.Errorf("expected value 'hello', but got '%s'", got)
+	}
+
+	err = reader.Set(ctx, "greeting", "bye", 0).Err()
+	if err == nil || !strings.Contains(err.Error(), "NOPERM") {
+		t.Errorf("expected a NOPERM error for a write, but got: %v", err)
+	}
+}
+
+// TestRedisWithTLS tests connecting over the TLS port with a self-signed certificate.
+func TestRedisWithTLS(t *testing.T) {
+	// Passing nil generates a self-signed certificate
+	client, cleanup := redistest.RunWithTLS(t, nil)
+	defer cleanup()
+
+	if client.Options().TLSConfig == nil {
+		t.Fatal("expected the client to be configured for TLS")
+	}
+
+	got, err := client.Ping(context.Background()).Result()
+	if err != nil {
+		t.Fatalf("failed to ping redis over TLS: %v", err)
+	}
+	if got != "PONG" {
+		t.Errorf("expected PONG, but got '%s'", got)
+	}
+}
+
+// TestRedisKeyspaceNotifications sets a key with a short TTL and waits for its expired event.
+func TestRedisKeyspaceNotifications(t *testing.T) {
+	client, cleanup := redistest.Run(t)
+	defer cleanup()
+
+	events, stop := redistest.EnableKeyspaceNotifications(t, client, "expired", "set")
+	defer stop()
+
+	ctx := context.Background()
+	if err := client.Set(ctx, "session:1", "alice", 100*time.Millisecond).Err(); err != nil {
+		t.Fatalf("failed to set key: %v", err)
+	}
+
+	want := []redistest.KeyEvent{
+		{Event: "set", Key: "session:1"},
+		{Event: "expired", Key: "session:1"},
+	}
+	for _, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Errorf("expected event %+v, but got %+v", w, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %+v", w)
+		}
+	}
+}
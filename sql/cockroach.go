@@ -0,0 +1,210 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	defaultCockroachImage = "cockroachdb/cockroach"
+	defaultCockroachTag   = "v24.2.4"
+	defaultCockroachPort  = "26257/tcp"
+	// Every node has to start and join the cluster before it serves queries.
+	defaultCockroachMaxWait = 3 * time.Minute
+)
+
+// RunCockroachCluster starts a CockroachDB cluster of nodes nodes using the default settings and
+// returns a connection to it along with a cleanup function that removes every node.
+// For more customization, use RunCockroachClusterWithOptions.
+func RunCockroachCluster(t testing.TB, nodes int) (*sql.DB, func()) {
+	return RunCockroachClusterWithOptions(t, nodes, nil)
+}
+
+// RunCockroachClusterWithOptions starts nodes CockroachDB containers on a dedicated Docker network,
+// joins them into a single insecure cluster and returns a connection to the first node as the root
+// user of the "defaultdb" database, along with a cleanup function that removes every node and the
+// network. It applies the default settings:
+//   - Repository: "cockroachdb/cockroach"
+//   - Tag: "v24.2.4"
+//
+// The connection is only returned once every node is reported live by the cluster, so that data
+// written through it is replicated across the nodes. The same runOpts and hostOpts are applied to
+// every node. The DSN is generated in the format:
+//
+//	"postgres://root@<actualPort>/defaultdb?sslmode=disable".
+func RunCockroachClusterWithOptions(t testing.TB, nodes int, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+
+	if nodes <= 0 {
+		t.Fatalf("cockroach cluster needs at least one node, got %d", nodes)
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+	pool.MaxWait = defaultCockroachMaxWait
+
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	network, err := pool.CreateNetwork("dockertestx-cockroach-" + suffix)
+	if err != nil {
+		t.Fatalf("failed to create docker network: %s", err)
+	}
+
+	var resources []*dockertest.Resource
+	var logs []*internal.LogCapture
+	purgeAll := func() {
+		for _, l := range logs {
+			l.Stop()
+		}
+		for _, resource := range resources {
+			if err := pool.Purge(resource); err != nil {
+				t.Logf("failed to remove cockroach container: %s", err)
+			}
+		}
+		if err := pool.RemoveNetwork(network); err != nil {
+			t.Logf("failed to remove docker network: %s", err)
+		}
+	}
+	dumpAll := func() {
+		for _, l := range logs {
+			l.Dump()
+		}
+	}
+
+	// Every node joins through the names of all nodes, which resolve on the shared network.
+	names := make([]string, nodes)
+	joins := make([]string, nodes)
+	for i := range names {
+		names[i] = fmt.Sprintf("dockertestx-cockroach-%d-%s", i, suffix)
+		joins[i] = names[i] + ":26257"
+	}
+
+	// Every node is started with the same options, so they share the settings.
+	var settings *internal.Settings
+	for i, name := range names {
+		// Set default run options for CockroachDB
+		defaultRunOpts := &dockertest.RunOptions{
+			Repository: defaultCockroachImage,
+			Tag:        defaultCockroachTag,
+			Name:       name,
+			Networks:   []*dockertest.Network{network},
+			Cmd: []string{
+				"start",
+				"--insecure",
+				"--join=" + strings.Join(joins, ","),
+				"--advertise-addr=" + joins[i],
+				"--listen-addr=:26257",
+				"--http-addr=:8080",
+			},
+		}
+
+		// Apply any provided RunOption functions to override defaults
+		for _, opt := range runOpts {
+			opt(defaultRunOpts)
+		}
+
+		settings = internal.TakeSettings(defaultRunOpts)
+
+		// Pass optional host configuration options
+		resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+		if err != nil {
+			purgeAll()
+			t.Fatalf("failed to start cockroach container %d: %s", i, err)
+		}
+		resources = append(resources, resource)
+		if err := settings.OnStart(pool, resource); err != nil {
+			purgeAll()
+			t.Fatalf("failed to configure cockroach container %d: %s", i, err)
+		}
+		logs = append(logs, internal.CaptureLogs(t.Logf, pool, resource, settings))
+	}
+
+	// The nodes wait for the cluster to be initialized before they serve queries.
+	if err := settings.Retry(pool, func() error {
+		return initCockroachCluster(resources[0], joins[0])
+	}); err != nil {
+		dumpAll()
+		purgeAll()
+		t.Fatalf("failed to initialize cockroach cluster: %s", err)
+	}
+
+	host, port, err := internal.HostAddress(pool, resources[0], defaultCockroachPort)
+	if err != nil {
+		purgeAll()
+		t.Fatalf("failed to get the address of the cockroach container: %s", err)
+	}
+	actualPort := net.JoinHostPort(host, port)
+	t.Logf("cockroach cluster of %d nodes is running on host port '%s'", nodes, actualPort)
+
+	dsn := fmt.Sprintf("postgres://root@%s/defaultdb?sslmode=disable", actualPort)
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		purgeAll()
+		t.Fatalf("failed to open cockroach connection: %s", err)
+	}
+
+	if err := settings.Retry(pool, func() error {
+		return cockroachNodesLive(db, nodes)
+	}); err != nil {
+		dumpAll()
+		_ = db.Close()
+		purgeAll()
+		t.Fatalf("could not connect to cockroach cluster: %s", err)
+	}
+	dsns.Store(db, dsn)
+
+	cleanup := func() {
+		dsns.Delete(db)
+		if err := db.Close(); err != nil {
+			t.Logf("failed to close DB: %s", err)
+		}
+		purgeAll()
+	}
+
+	return db, cleanup
+}
+
+// initCockroachCluster runs "cockroach init" inside the container of the first node. A cluster that
+// has already been initialized, e.g. by an earlier attempt whose reply was lost, is not an error.
+func initCockroachCluster(resource *dockertest.Resource, host string) error {
+	var stderr bytes.Buffer
+	code, err := resource.Exec([]string{"cockroach", "init", "--insecure", "--host=" + host}, dockertest.ExecOptions{
+		StdErr: &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run cockroach init: %w", err)
+	}
+	if code != 0 {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "already been initialized") {
+			return nil
+		}
+		return fmt.Errorf("cockroach init exited with code %d: %s", code, msg)
+	}
+	return nil
+}
+
+// cockroachNodesLive returns nil once the cluster reports nodes live nodes.
+func cockroachNodesLive(db *sql.DB, nodes int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var live int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM crdb_internal.gossip_nodes WHERE is_live").Scan(&live); err != nil {
+		return err
+	}
+	if live < nodes {
+		return fmt.Errorf("%d of %d nodes are live", live, nodes)
+	}
+	return nil
+}
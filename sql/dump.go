@@ -0,0 +1,111 @@
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/vvatanabe/dockertestx/internal"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// DumpPostgres runs pg_dump inside the given PostgreSQL container and writes the plain SQL dump of
+// the POSTGRES_DB database to outPath on the host. Rows are written as INSERT statements rather than
+// COPY blocks so that the dump is easy to compare in golden-file tests.
+// The resource can be obtained with the dockertestx.WithResource RunOption.
+// If pg_dump fails or the file cannot be written, it returns an error.
+func DumpPostgres(t testing.TB, resource *dockertest.Resource, outPath string) error {
+	t.Helper()
+
+	user, db := postgresUserDB(resource)
+	return dump(resource, outPath, []string{"pg_dump", "--username", user, "--inserts", "--no-owner", db}, nil)
+}
+
+// RestorePostgresDump restores the dump at dumpPath on the host into the POSTGRES_DB database of the
+// given PostgreSQL container, e.g. a dump of a production database written by pg_dump. The dump is
+// streamed into the container through the standard input of the restore command, so nothing has to
+// be mounted. Plain SQL dumps are run with psql and custom-format dumps (pg_dump -Fc), recognized by
+// their "PGDMP" header, with pg_restore; ownership is not restored. Restore into a fresh container or
+// an empty database, as objects that already exist make the restore fail.
+// The resource can be obtained with the dockertestx.WithResource RunOption.
+// If the file cannot be read or a statement fails, it returns an error.
+func RestorePostgresDump(t testing.TB, resource *dockertest.Resource, dumpPath string) error {
+	t.Helper()
+
+	data, err := os.ReadFile(dumpPath)
+	if err != nil {
+		return fmt.Errorf("failed to read dump '%s': %w", dumpPath, err)
+	}
+
+	user, db := postgresUserDB(resource)
+	cmd := []string{"psql", "--username", user, "--dbname", db, "--quiet", "--set", "ON_ERROR_STOP=1"}
+	if bytes.HasPrefix(data, []byte("PGDMP")) {
+		cmd = []string{"pg_restore", "--username", user, "--dbname", db, "--no-owner", "--exit-on-error"}
+	}
+
+	var stderr bytes.Buffer
+	code, err := resource.Exec(cmd, dockertest.ExecOptions{
+		StdIn:  bytes.NewReader(data),
+		StdOut: io.Discard,
+		StdErr: &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", cmd[0], err)
+	}
+	if code != 0 {
+		return fmt.Errorf("%s exited with code %d: %s", cmd[0], code, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// postgresUserDB returns the user and database configured for the given PostgreSQL container,
+// falling back to the defaults of the postgres image.
+func postgresUserDB(resource *dockertest.Resource) (user, db string) {
+	env := resource.Container.Config.Env
+	user = internal.GetEnvValue(env, "POSTGRES_USER")
+	if user == "" {
+		user = "postgres"
+	}
+	db = internal.GetEnvValue(env, "POSTGRES_DB")
+	if db == "" {
+		db = user
+	}
+	return user, db
+}
+
+// DumpMySQL runs mysqldump inside the given MySQL container and writes the dump of the MYSQL_DATABASE
+// database to outPath on the host. The dump date is omitted so that the output is stable across runs.
+// The resource can be obtained with the dockertestx.WithResource RunOption.
+// If mysqldump fails or the file cannot be written, it returns an error.
+func DumpMySQL(t testing.TB, resource *dockertest.Resource, outPath string) error {
+	t.Helper()
+
+	env := resource.Container.Config.Env
+	pass := internal.GetEnvValue(env, "MYSQL_ROOT_PASSWORD")
+	db := internal.GetEnvValue(env, "MYSQL_DATABASE")
+
+	// The password is passed through the environment to keep it off the command line.
+	return dump(resource, outPath, []string{"mysqldump", "--user=root", "--skip-dump-date", "--no-tablespaces", db}, []string{"MYSQL_PWD=" + pass})
+}
+
+// dump executes cmd inside the container and writes its standard output to outPath.
+func dump(resource *dockertest.Resource, outPath string, cmd, env []string) error {
+	var stdout, stderr bytes.Buffer
+	code, err := resource.Exec(cmd, dockertest.ExecOptions{
+		Env:    env,
+		StdOut: &stdout,
+		StdErr: &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", cmd[0], err)
+	}
+	if code != 0 {
+		return fmt.Errorf("%s exited with code %d: %s", cmd[0], code, strings.TrimSpace(stderr.String()))
+	}
+	if err := os.WriteFile(outPath, stdout.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write dump to '%s': %w", outPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// ExecOption configures how ExecSQLFile and ExecSQLReader execute a script.
+type ExecOption func(*execOptions)
+
+// execOptions holds the settings applied by ExecOption functions.
+type execOptions struct {
+	split   bool
+	dialect Dialect
+}
+
+// WithSplitStatements returns an ExecOption that splits the script into individual statements and
+// executes them one by one, like InitialDBSetup.SplitStatements. Enable it for drivers that reject
+// several statements in a single Exec, such as MySQL without multiStatements. The quoting rules
+// follow the dialect of db's driver; see WithDialect.
+func WithSplitStatements() ExecOption {
+	return func(o *execOptions) {
+		o.split = true
+	}
+}
+
+// WithDialect returns an ExecOption that splits the script with the quoting rules of dialect
+// instead of those picked from db's driver, e.g. DialectPostgres for a PostgreSQL driver other than
+// lib/pq and pgx. It only matters together with WithSplitStatements.
+func WithDialect(dialect Dialect) ExecOption {
+	return func(o *execOptions) {
+		o.dialect = dialect
+	}
+}
+
+// ExecSQLFile reads the SQL script at path, e.g. a teardown script or a single migration, and
+// executes it against db. By default the whole script is sent in a single Exec; pass
+// WithSplitStatements to execute its statements one by one.
+// If the file cannot be read or a statement fails, it returns an error.
+func ExecSQLFile(t testing.TB, db *sql.DB, path string, opts ...ExecOption) error {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open SQL file: %w", err)
+	}
+	defer f.Close()
+
+	if err := ExecSQLReader(t, db, f, opts...); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// ExecSQLReader is like ExecSQLFile but reads the SQL script from r.
+func ExecSQLReader(t testing.TB, db *sql.DB, r io.Reader, opts ...ExecOption) error {
+	t.Helper()
+
+	o := execOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	script, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read SQL script: %w", err)
+	}
+
+	if !o.split {
+		if _, err := db.Exec(string(script)); err != nil {
+			return fmt.Errorf("failed to execute SQL script: %w", err)
+		}
+		return nil
+	}
+	for i, stmt := range splitStatements(string(script), dialectOf(db, o.dialect)) {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to execute SQL statement %d (%s): %w", i, previewSQL(stmt), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+package sql
+
+var SplitStatements = splitStatements
+var PostgresReady = postgresReady
+var MySQLRunOptions = mysqlRunOptions
+var EscapeMySQLString = escapeMySQLString
+var DialectOf = dialectOf
@@ -0,0 +1,162 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RunMySQLPrimaryReplica starts a MySQL primary and a read replica using the default settings
+// and returns a connection to each along with a cleanup function.
+// For more customization, use RunMySQLPrimaryReplicaWithOptions.
+func RunMySQLPrimaryReplica(t testing.TB) (primary, replica *sql.DB, cleanup func()) {
+	return RunMySQLPrimaryReplicaWithOptions(t, nil)
+}
+
+// RunMySQLPrimaryReplicaWithOptions starts two MySQL containers on a dedicated Docker network and
+// configures GTID based binlog replication from the first (primary) to the second (replica).
+// It returns once the replica reports both its I/O and SQL threads as running (SHOW REPLICA STATUS,
+// formerly SHOW SLAVE STATUS). Replication is asynchronous, so a row written to the primary becomes
+// visible on the replica only after a short lag.
+//
+// The replica runs with read_only enabled; the root user the connections use can still write to it.
+// The given runOpts and hostOpts are applied to both containers, after the replication settings.
+func RunMySQLPrimaryReplicaWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (primary, replica *sql.DB, cleanup func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+	network, err := pool.CreateNetwork("dockertestx-mysql-" + suffix)
+	if err != nil {
+		t.Fatalf("failed to create docker network: %s", err)
+	}
+	var cleanups []func()
+	cleanupAll := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+		if err := pool.RemoveNetwork(network); err != nil {
+			t.Logf("failed to remove docker network: %s", err)
+		}
+	}
+
+	primaryName := "dockertestx-mysql-primary-" + suffix
+	// Both nodes get the same options, so the settings recorded for the last one started also
+	// govern the replication wait. They are taken from the node's options by TryRunMySQL.
+	var settings *internal.Settings
+	node := func(name string, cmd ...string) []func(*dockertest.RunOptions) {
+		opts := append([]func(*dockertest.RunOptions){func(opts *dockertest.RunOptions) {
+			opts.Name = name
+			opts.Networks = append(opts.Networks, network)
+			opts.Cmd = append(opts.Cmd, cmd...)
+		}}, runOpts...)
+		return append(opts, func(opts *dockertest.RunOptions) {
+			settings = internal.SettingsOf(opts)
+		})
+	}
+
+	primary, primaryCleanup, err := TryRunMySQL(node(primaryName,
+		"--server-id=1",
+		"--log-bin=mysql-bin",
+		"--gtid-mode=ON",
+		"--enforce-gtid-consistency=ON",
+	), hostOpts...)
+	if err != nil {
+		cleanupAll()
+		t.Fatalf("failed to start mysql primary: %s", err)
+	}
+	cleanups = append(cleanups, primaryCleanup)
+
+	replica, replicaCleanup, err := TryRunMySQL(node("dockertestx-mysql-replica-"+suffix,
+		"--server-id=2",
+		"--relay-log=relay-bin",
+		"--gtid-mode=ON",
+		"--enforce-gtid-consistency=ON",
+		"--read-only=ON",
+	), hostOpts...)
+	if err != nil {
+		cleanupAll()
+		t.Fatalf("failed to start mysql replica: %s", err)
+	}
+	cleanups = append(cleanups, replicaCleanup)
+
+	// Both nodes share the root password, so read it back from the primary's DSN.
+	pass := MySQLConfig(t, primary).Passwd
+
+	if _, err := replica.Exec(fmt.Sprintf(
+		"CHANGE REPLICATION SOURCE TO SOURCE_HOST='%s', SOURCE_PORT=3306, SOURCE_USER='root', SOURCE_PASSWORD='%s', SOURCE_AUTO_POSITION=1, GET_SOURCE_PUBLIC_KEY=1",
+		primaryName, escapeMySQLString(pass),
+	)); err != nil {
+		cleanupAll()
+		t.Fatalf("failed to configure replication source: %s", err)
+	}
+	if _, err := replica.Exec("START REPLICA"); err != nil {
+		cleanupAll()
+		t.Fatalf("failed to start replication: %s", err)
+	}
+
+	if settings.ReadyTimeout > 0 {
+		pool.MaxWait = settings.ReadyTimeout
+	}
+	if err := settings.Retry(pool, func() error {
+		return replicaRunning(replica)
+	}); err != nil {
+		cleanupAll()
+		t.Fatalf("replication did not start: %s", err)
+	}
+	t.Logf("mysql replica is replicating from '%s'", primaryName)
+
+	return primary, replica, cleanupAll
+}
+
+// replicaRunning returns nil if SHOW REPLICA STATUS reports both replication threads as running.
+func replicaRunning(db *sql.DB) error {
+	rows, err := db.Query("SHOW REPLICA STATUS")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("replication is not configured")
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+
+	status := make(map[string]string, len(cols))
+	for i, col := range cols {
+		status[col] = string(values[i])
+	}
+	if status["Replica_IO_Running"] != "Yes" || status["Replica_SQL_Running"] != "Yes" {
+		return fmt.Errorf("replication threads are not running (io: %q, sql: %q, last error: %q)",
+			status["Replica_IO_Running"], status["Replica_SQL_Running"], status["Last_IO_Error"])
+	}
+	return nil
+}
+
+// escapeMySQLString escapes s for use inside a single-quoted MySQL string literal.
+func escapeMySQLString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(s)
+}
@@ -0,0 +1,61 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// schemaSeq distinguishes schemas created within the same nanosecond by parallel tests.
+var schemaSeq atomic.Uint64
+
+// NewPostgresSchema creates a uniquely named schema in the database behind db and returns a new
+// *sql.DB whose connections use that schema as their search_path, along with a cleanup function
+// that closes the connections and drops the schema with everything in it.
+//
+// It lets parallel tests share one PostgreSQL container without interfering with each other:
+// unqualified table names resolve to the test's own schema. db must have been returned by
+// RunPostgres or a related function of this package, because the new connections are opened
+// with the same DSN.
+func NewPostgresSchema(t testing.TB, db *sql.DB) (*sql.DB, func()) {
+	t.Helper()
+
+	v, ok := dsns.Load(db)
+	if !ok {
+		t.Fatal("db was not started by the dockertestx sql package")
+	}
+	dsn := v.(string)
+
+	schema := fmt.Sprintf("test_%x_%d", time.Now().UnixNano(), schemaSeq.Add(1))
+	if _, err := db.Exec(fmt.Sprintf(`CREATE SCHEMA "%s"`, schema)); err != nil {
+		t.Fatalf("failed to create schema '%s': %s", schema, err)
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	schemaDB, err := sql.Open("postgres", dsn+sep+"search_path="+schema)
+	if err == nil {
+		err = schemaDB.Ping()
+	}
+	if err != nil {
+		_, _ = db.Exec(fmt.Sprintf(`DROP SCHEMA "%s" CASCADE`, schema))
+		t.Fatalf("failed to connect to schema '%s': %s", schema, err)
+	}
+	t.Logf("postgres schema '%s' is ready", schema)
+
+	cleanup := func() {
+		if err := schemaDB.Close(); err != nil {
+			t.Logf("failed to close DB: %s", err)
+		}
+		if _, err := db.Exec(fmt.Sprintf(`DROP SCHEMA "%s" CASCADE`, schema)); err != nil {
+			t.Logf("failed to drop schema '%s': %s", schema, err)
+		}
+	}
+
+	return schemaDB, cleanup
+}
@@ -0,0 +1,111 @@
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// SeedJSON inserts the rows of a JSON array of objects read from r into tableName.
+// The columns are the union of the keys of all objects, and every row is inserted with a
+// parameterized statement inside a single transaction, so either all rows are inserted or none.
+// Table and column names are used verbatim.
+//
+// Values are converted as follows:
+//   - null, and keys that are missing from an object, insert NULL
+//   - strings and booleans are passed through
+//   - integral numbers become int64 and other numbers float64
+//   - nested objects and arrays are encoded as JSON text, e.g. for JSON or JSONB columns
+//
+// Conversion to the column types is left to the database, so e.g. a string holding a
+// timestamp can be inserted into a timestamp column.
+// If the input cannot be decoded or a row cannot be inserted, it returns an error.
+func SeedJSON(t testing.TB, db *sql.DB, tableName string, r io.Reader) error {
+	t.Helper()
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var rows []map[string]any
+	if err := dec.Decode(&rows); err != nil {
+		return fmt.Errorf("failed to decode JSON rows for '%s': %w", tableName, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = placeholder(db, i+1)
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		tableName, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for i, row := range rows {
+		args := make([]any, len(columns))
+		for j, col := range columns {
+			v, err := jsonValue(row[col])
+			if err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("failed to convert column '%s' of row %d: %w", col, i, err)
+			}
+			args[j] = v
+		}
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to insert row %d into '%s': %w", i, tableName, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// placeholder returns the n-th (one-based) bind parameter in the syntax of db's driver.
+func placeholder(db *sql.DB, n int) string {
+	if _, ok := db.Driver().(*pq.Driver); ok {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// jsonValue converts a value decoded with UseNumber into a database/sql argument.
+func jsonValue(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		// nil, string and bool
+		return v, nil
+	}
+}
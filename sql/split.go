@@ -0,0 +1,193 @@
+package sql
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"reflect"
+	"strings"
+)
+
+// Dialect selects the quoting rules used to split a SQL script into statements.
+type Dialect int
+
+const (
+	// DialectAuto picks DialectPostgres for the lib/pq and pgx drivers and DialectMySQL for any
+	// other driver. State the dialect explicitly for other PostgreSQL drivers.
+	DialectAuto Dialect = iota
+	// DialectMySQL lets a backslash escape the next character in string literals. It also suits
+	// ClickHouse.
+	DialectMySQL
+	// DialectPostgres follows PostgreSQL with standard_conforming_strings=on, the default: a
+	// backslash only escapes inside E'...' literals, and $$ ... $$ or $tag$ ... $tag$ quote a body.
+	// It also suits CockroachDB and TimescaleDB.
+	DialectPostgres
+)
+
+// dialectOf resolves DialectAuto from the driver of db.
+func dialectOf(db *sql.DB, d Dialect) Dialect {
+	if d != DialectAuto {
+		return d
+	}
+	drv := db.Driver()
+	if _, ok := drv.(*pq.Driver); ok {
+		return DialectPostgres
+	}
+	// pgx is matched by package so that this package does not depend on it.
+	typ := reflect.TypeOf(drv)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if strings.HasPrefix(typ.PkgPath(), "github.com/jackc/pgx/") {
+		return DialectPostgres
+	}
+	return DialectMySQL
+}
+
+// splitStatements splits a SQL script written in dialect, which must not be DialectAuto, into
+// individual statements. It understands the following, so that semicolons inside them do not end
+// a statement:
+//   - single-quoted, double-quoted and backtick-quoted strings, including doubled quotes and
+//     backslash escapes; for DialectPostgres, a backslash only escapes inside E'...' literals
+//   - "--" line comments and "/* */" block comments, which are removed; MySQL "/*! */" and
+//     "/*+ */" comments are kept because they carry meaning
+//   - for DialectPostgres, dollar-quoted bodies such as $$ ... $$ or $fn$ ... $fn$; MySQL allows
+//     $ in unquoted identifiers, so it is an ordinary character for DialectMySQL
+//   - MySQL client style "DELIMITER //" lines, which change the statement delimiter, e.g. around
+//     a stored procedure body
+//
+// Empty statements are dropped and the remaining ones are returned without their delimiter.
+func splitStatements(script string, dialect Dialect) []string {
+	postgres := dialect == DialectPostgres
+	var (
+		stmts []string
+		cur   strings.Builder
+		delim = ";"
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		cur.Reset()
+	}
+
+	n := len(script)
+	for i := 0; i < n; {
+		rest := script[i:]
+
+		// A DELIMITER directive is only recognized at the start of a statement.
+		if strings.TrimSpace(cur.String()) == "" && len(rest) > len("DELIMITER ") && strings.EqualFold(rest[:len("DELIMITER ")], "DELIMITER ") {
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			if d := strings.TrimSpace(rest[len("DELIMITER "):end]); d != "" {
+				delim = d
+			}
+			cur.Reset()
+			i += end
+			continue
+		}
+
+		switch c := script[i]; {
+		case strings.HasPrefix(rest, delim):
+			flush()
+			i += len(delim)
+		case c == '\'' || c == '"' || c == '`':
+			// Postgres interprets backslashes only in escape string constants such as E'a\'b'.
+			escapes := !postgres || c == '\'' && escapeStringPrefix(script, i)
+			j := skipQuoted(script, i, escapes)
+			cur.WriteString(script[i:j])
+			i = j
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			if strings.HasPrefix(rest, "/*!") || strings.HasPrefix(rest, "/*+") {
+				cur.WriteString(rest[:end])
+			} else {
+				cur.WriteByte(' ')
+			}
+			i += end
+		case c == '$' && postgres:
+			if tag := dollarTag(rest); tag != "" {
+				end := strings.Index(rest[len(tag):], tag)
+				if end < 0 {
+					end = len(rest)
+				} else {
+					end += 2 * len(tag)
+				}
+				cur.WriteString(rest[:end])
+				i += end
+				continue
+			}
+			cur.WriteByte(c)
+			i++
+		default:
+			cur.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return stmts
+}
+
+// escapeStringPrefix reports whether the quote at script[quote] opens a Postgres escape string
+// constant, i.e. is preceded by an E or e that is not the end of a longer identifier.
+func escapeStringPrefix(script string, quote int) bool {
+	if quote == 0 || script[quote-1] != 'E' && script[quote-1] != 'e' {
+		return false
+	}
+	if quote == 1 {
+		return true
+	}
+	c := script[quote-2]
+	return !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80)
+}
+
+// skipQuoted returns the index just past the quoted string that starts at script[start].
+// Doubled quotes and, if escapes is true and the string is not backtick-quoted, backslash
+// escapes do not end the string.
+func skipQuoted(script string, start int, escapes bool) int {
+	q := script[start]
+	for j := start + 1; j < len(script); j++ {
+		switch script[j] {
+		case '\\':
+			if escapes && q != '`' {
+				j++
+			}
+		case q:
+			if j+1 < len(script) && script[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(script)
+}
+
+// dollarTag returns the Postgres dollar-quote tag ("$$" or "$name$") at the start of s,
+// or an empty string if s does not start with one. Positional parameters such as $1 are not tags.
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		switch {
+		case c == '$':
+			return s[:j+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		case c >= '0' && c <= '9' && j > 1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -3,14 +3,19 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	_ "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/go-sql-driver/mysql"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/vvatanabe/dockertestx/internal"
 	"io"
 	"log"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -26,50 +31,121 @@ func init() {
 // It returns a connected *sql.DB and a cleanup function.
 func RunDockerDB(t testing.TB, runOpts *dockertest.RunOptions, containerPort, driverName string, dsnFunc func(actualPort string) string, hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
 	t.Helper()
+	return RunDockerDBContext(context.Background(), t, runOpts, containerPort, driverName, dsnFunc, hostOpts...)
+}
+
+// RunDockerDBContext is like RunDockerDB, but it aborts the image pull and the readiness retry
+// as soon as ctx is done, purging any container that was already created.
+func RunDockerDBContext(ctx context.Context, t testing.TB, runOpts *dockertest.RunOptions, containerPort, driverName string, dsnFunc func(actualPort string) string, hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+
+	db, cleanup, err := runDockerDB(ctx, t.Logf, runOpts, containerPort, driverName, dsnFunc, pingReady, hostOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, cleanup
+}
+
+// TryRunDockerDB is like RunDockerDB, but it does not require a testing.TB and returns an error
+// instead of failing the test. It can be used where no *testing.T is available, e.g. in TestMain
+// or a shared fixture constructor. Errors during cleanup are ignored.
+func TryRunDockerDB(runOpts *dockertest.RunOptions, containerPort, driverName string, dsnFunc func(actualPort string) string, hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	return TryRunDockerDBContext(context.Background(), runOpts, containerPort, driverName, dsnFunc, hostOpts...)
+}
+
+// TryRunDockerDBContext is like TryRunDockerDB, but it honors cancellation of ctx like RunDockerDBContext.
+// When ctx is done before the database is ready, the returned error wraps ctx.Err().
+func TryRunDockerDBContext(ctx context.Context, runOpts *dockertest.RunOptions, containerPort, driverName string, dsnFunc func(actualPort string) string, hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	return runDockerDB(ctx, func(string, ...any) {}, runOpts, containerPort, driverName, dsnFunc, pingReady, hostOpts...)
+}
+
+// dsns maps each *sql.DB returned by runDockerDB to the DSN it was opened with, so that
+// helpers such as NewPostgresSchema can open further connections to the same database.
+var dsns sync.Map
+
+// runDockerDB implements the RunDockerDB family. The database is considered ready once ready
+// returns nil. Progress and cleanup failures are reported through logf.
+func runDockerDB(ctx context.Context, logf func(format string, args ...any), runOpts *dockertest.RunOptions, containerPort, driverName string, dsnFunc func(actualPort string) string, ready func(ctx context.Context, db *sql.DB) error, hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	settings := internal.TakeSettings(runOpts)
 
 	pool, err := dockertest.NewPool("")
 	if err != nil {
-		t.Fatalf("failed to connect to docker: %s", err)
+		return nil, nil, fmt.Errorf("failed to connect to docker: %w", err)
+	}
+
+	// Pull the image up front so that a long pull can be cancelled.
+	if err := internal.PullImage(ctx, pool, runOpts); err != nil {
+		return nil, nil, fmt.Errorf("failed to pull %s image: %w", driverName, err)
 	}
 
 	// Pass optional host configuration options.
 	resource, err := pool.RunWithOptions(runOpts, hostOpts...)
 	if err != nil {
-		t.Fatalf("failed to start %s container: %s", driverName, err)
+		return nil, nil, fmt.Errorf("failed to start %s container: %w", driverName, err)
+	}
+	if err := ctx.Err(); err != nil {
+		_ = pool.Purge(resource)
+		return nil, nil, fmt.Errorf("failed to start %s container: %w", driverName, err)
 	}
 
 	actualPort := resource.GetHostPort(containerPort)
 	if actualPort == "" {
 		_ = pool.Purge(resource)
-		t.Fatalf("no host port was assigned for the %s container", driverName)
+		return nil, nil, fmt.Errorf("no host port was assigned for the %s container", driverName)
 	}
-	t.Logf("%s container is running on host port '%s'", driverName, actualPort)
+	logf("%s container is running on host port '%s'", driverName, actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		return nil, nil, fmt.Errorf("failed to configure %s container: %w", driverName, err)
+	}
+	logs := internal.CaptureLogs(logf, pool, resource, settings)
 
 	var db *sql.DB
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	if err = pool.Retry(func() error {
-		dsn := dsnFunc(actualPort)
+	dsn := dsnFunc(actualPort)
+	if err = settings.RetryContext(ctx, pool, func() error {
+		// Each attempt gets its own deadline so that a server which is still initializing
+		// (e.g. creating its default databases) does not exhaust the whole retry budget.
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
 		db, err = sql.Open(driverName, dsn)
 		if err != nil {
 			return err
 		}
-		return db.PingContext(ctx)
+		if p := settings.DBPool; p != nil {
+			db.SetMaxOpenConns(p.MaxOpen)
+			db.SetMaxIdleConns(p.MaxIdle)
+			db.SetConnMaxLifetime(p.MaxLifetime)
+		}
+		if err := ready(ctx, db); err != nil {
+			_ = db.Close()
+			return err
+		}
+		return nil
 	}); err != nil {
+		logs.Dump()
 		_ = pool.Purge(resource)
-		t.Fatalf("failed to connect to %s: %s", driverName, err)
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", driverName, err)
 	}
+	dsns.Store(db, dsn)
 
 	cleanup := func() {
+		logs.Stop()
+		dsns.Delete(db)
 		if err := db.Close(); err != nil {
-			t.Logf("failed to close DB: %s", err)
+			logf("failed to close DB: %s", err)
 		}
 		if err := pool.Purge(resource); err != nil {
-			t.Logf("failed to remove %s container: %s", driverName, err)
+			logf("failed to remove %s container: %s", driverName, err)
 		}
 	}
 
-	return db, cleanup
+	return db, cleanup, nil
+}
+
+// pingReady reports whether the database answers a ping.
+func pingReady(ctx context.Context, db *sql.DB) error {
+	return db.PingContext(ctx)
 }
 
 // RunMySQL starts a MySQL Docker container using the default settings and returns a connected *sql.DB
@@ -82,6 +158,8 @@ func RunMySQL(t testing.TB) (*sql.DB, func()) {
 const (
 	defaultMySQLImage = "mysql"
 	defaultMySQLTag   = "8.0"
+	// defaultMySQLPassword is the root password used unless MYSQL_ROOT_PASSWORD is set.
+	defaultMySQLPassword = "secret"
 )
 
 // RunMySQLWithOptions starts a MySQL Docker container using Docker and returns a connected *sql.DB
@@ -95,13 +173,43 @@ const (
 // The DSN is generated in the format:
 //
 //	"root:<MYSQL_ROOT_PASSWORD>@tcp(<actualPort>)/<MYSQL_DATABASE>?parseTime=true".
+//
+// When WithMySQLCharset is used, charset and collation parameters are appended to the DSN.
 func RunMySQLWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+	return RunMySQLContext(context.Background(), t, runOpts, hostOpts...)
+}
+
+// RunMySQLContext is like RunMySQLWithOptions, but it aborts startup as soon as ctx is done.
+// See RunDockerDBContext.
+func RunMySQLContext(ctx context.Context, t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+
+	opts, dsnFunc := mysqlRunOptions(runOpts)
+	return RunDockerDBContext(ctx, t, opts, "3306/tcp", "mysql", dsnFunc, hostOpts...)
+}
+
+// TryRunMySQL is like RunMySQLWithOptions, but it returns an error instead of failing the test.
+// See TryRunDockerDB.
+func TryRunMySQL(runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	return TryRunMySQLContext(context.Background(), runOpts, hostOpts...)
+}
+
+// TryRunMySQLContext is like TryRunMySQL, but it aborts startup as soon as ctx is done.
+// See TryRunDockerDBContext.
+func TryRunMySQLContext(ctx context.Context, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	opts, dsnFunc := mysqlRunOptions(runOpts)
+	return TryRunDockerDBContext(ctx, opts, "3306/tcp", "mysql", dsnFunc, hostOpts...)
+}
+
+// mysqlRunOptions builds the MySQL run options and the matching DSN function.
+func mysqlRunOptions(runOpts []func(*dockertest.RunOptions)) (*dockertest.RunOptions, func(actualPort string) string) {
 	// Set default run options for MySQL.
 	defaultRunOpts := &dockertest.RunOptions{
 		Repository: defaultMySQLImage,
 		Tag:        defaultMySQLTag,
 		Env: []string{
-			"MYSQL_ROOT_PASSWORD=secret",
+			"MYSQL_ROOT_PASSWORD=" + defaultMySQLPassword,
 			"MYSQL_DATABASE=test",
 		},
 	}
@@ -111,17 +219,96 @@ func RunMySQLWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), h
 		opt(defaultRunOpts)
 	}
 
-	pass := internal.GetEnvValue(defaultRunOpts.Env, "MYSQL_ROOT_PASSWORD")
+	// A RunOption that replaces Env, e.g. only to set MYSQL_DATABASE, keeps the default root password
+	// unless it asks for an empty one.
+	pass, ok := internal.LookupEnvValue(defaultRunOpts.Env, "MYSQL_ROOT_PASSWORD")
+	if !ok && internal.GetEnvValue(defaultRunOpts.Env, "MYSQL_ALLOW_EMPTY_PASSWORD") == "" {
+		pass = defaultMySQLPassword
+		defaultRunOpts.Env = append(defaultRunOpts.Env, "MYSQL_ROOT_PASSWORD="+pass)
+	}
 	db := internal.GetEnvValue(defaultRunOpts.Env, "MYSQL_DATABASE")
 
-	return RunDockerDB(t, defaultRunOpts, "3306/tcp", "mysql", func(actualPort string) string {
-		return fmt.Sprintf("root:%s@tcp(%s)/%s?parseTime=true", pass, actualPort, db)
-	}, hostOpts...)
+	params := "parseTime=true"
+	if charset := internal.GetCmdFlagValue(defaultRunOpts.Cmd, "--character-set-server"); charset != "" {
+		params += "&charset=" + charset
+	}
+	if collation := internal.GetCmdFlagValue(defaultRunOpts.Cmd, "--collation-server"); collation != "" {
+		params += "&collation=" + collation
+	}
+
+	return defaultRunOpts, func(actualPort string) string {
+		return fmt.Sprintf("root:%s@tcp(%s)/%s?%s", pass, actualPort, db, params)
+	}
+}
+
+// WithDBPool returns a RunOption that configures the connection pool of the returned *sql.DB, as
+// db.SetMaxOpenConns, db.SetMaxIdleConns and db.SetConnMaxLifetime would. A value of zero or less
+// for maxOpen or maxLifetime means no limit, and for maxIdle no idle connections are kept. Limiting
+// the pool keeps concurrent tests from exhausting the server's connections: queries beyond maxOpen
+// wait for a free connection instead of failing. It is honored by RunDockerDB and the Run functions
+// built on it, such as RunMySQLWithOptions and RunPostgresWithOptions.
+func WithDBPool(maxOpen, maxIdle int, maxLifetime time.Duration) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		internal.SettingsOf(opts).DBPool = &internal.DBPool{
+			MaxOpen:     maxOpen,
+			MaxIdle:     maxIdle,
+			MaxLifetime: maxLifetime,
+		}
+	}
+}
+
+// WithMySQLCharset returns a RunOption that starts the MySQL server with the given default
+// character set and collation (--character-set-server and --collation-server) and connects with
+// the same charset and collation, e.g. WithMySQLCharset("utf8mb4", "utf8mb4_unicode_ci").
+// An empty collation leaves the server default for the charset.
+func WithMySQLCharset(charset, collation string) func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Cmd = append(opts.Cmd, "--character-set-server="+charset)
+		if collation != "" {
+			opts.Cmd = append(opts.Cmd, "--collation-server="+collation)
+		}
+	}
+}
+
+// WithMySQLBinlog returns a RunOption that starts the MySQL server with row based binary logging and
+// GTIDs enabled (--log-bin, --binlog-format=ROW, --gtid-mode=ON, --enforce-gtid-consistency=ON and
+// --server-id=1), as change data capture consumers require. A binlog reader connects to the same
+// address and credentials as the returned *sql.DB; see MySQLConfig. It must register with a
+// server ID other than 1.
+func WithMySQLBinlog() func(*dockertest.RunOptions) {
+	return func(opts *dockertest.RunOptions) {
+		opts.Cmd = append(opts.Cmd,
+			"--server-id=1",
+			"--log-bin=mysql-bin",
+			"--binlog-format=ROW",
+			"--gtid-mode=ON",
+			"--enforce-gtid-consistency=ON",
+		)
+	}
+}
+
+// MySQLConfig returns the connection settings of db, which must have been returned by RunMySQL or
+// a related function of this package. Its Addr field holds the host and the mapped port of the
+// container, e.g. for clients that connect without database/sql such as binlog readers.
+func MySQLConfig(t testing.TB, db *sql.DB) *mysql.Config {
+	t.Helper()
+
+	v, ok := dsns.Load(db)
+	if !ok {
+		t.Fatal("db was not started by the dockertestx sql package")
+	}
+	cfg, err := mysql.ParseDSN(v.(string))
+	if err != nil {
+		t.Fatalf("failed to parse mysql DSN: %s", err)
+	}
+	return cfg
 }
 
 const (
 	defaultPostgresImage = "postgres"
 	defaultPostgresTag   = "13"
+	// defaultPostgresPassword is the password used unless POSTGRES_PASSWORD is set.
+	defaultPostgresPassword = "secret"
 )
 
 // RunPostgres starts a PostgreSQL Docker container using the default settings and returns a connected *sql.DB
@@ -143,12 +330,58 @@ func RunPostgres(t testing.TB) (*sql.DB, func()) {
 //
 //	"postgres://postgres:<POSTGRES_PASSWORD>@<actualPort>/<POSTGRES_DB>?sslmode=disable".
 func RunPostgresWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+	return RunPostgresContext(context.Background(), t, runOpts, hostOpts...)
+}
+
+// RunPostgresContext is like RunPostgresWithOptions, but it aborts startup as soon as ctx is done.
+// See RunDockerDBContext.
+func RunPostgresContext(ctx context.Context, t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+
+	opts, dsnFunc := postgresRunOptions(runOpts)
+	db, cleanup, err := runDockerDB(ctx, t.Logf, opts, "5432/tcp", "postgres", dsnFunc, postgresReady, hostOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, cleanup
+}
+
+// TryRunPostgres is like RunPostgresWithOptions, but it returns an error instead of failing the test.
+// See TryRunDockerDB.
+func TryRunPostgres(runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	return TryRunPostgresContext(context.Background(), runOpts, hostOpts...)
+}
+
+// TryRunPostgresContext is like TryRunPostgres, but it aborts startup as soon as ctx is done.
+// See TryRunDockerDBContext.
+func TryRunPostgresContext(ctx context.Context, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	opts, dsnFunc := postgresRunOptions(runOpts)
+	return runDockerDB(ctx, func(string, ...any) {}, opts, "5432/tcp", "postgres", dsnFunc, postgresReady, hostOpts...)
+}
+
+// postgresReady reports whether PostgreSQL answers queries. A ping alone is not enough: a server
+// that is still starting up, shutting down after its init scripts or recovering accepts connections
+// but rejects them with SQLSTATE 57P03 (cannot_connect_now), which is retried until the server has
+// settled. Authentication failures (SQLSTATE class 28) cannot resolve themselves and stop the retry.
+func postgresReady(ctx context.Context, db *sql.DB) error {
+	var one int
+	err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Class() == "28" {
+		return backoff.Permanent(err)
+	}
+	return err
+}
+
+// postgresRunOptions builds the PostgreSQL run options and the matching DSN function.
+func postgresRunOptions(runOpts []func(*dockertest.RunOptions)) (*dockertest.RunOptions, func(actualPort string) string) {
 	// Set default run options for PostgreSQL.
 	defaultRunOpts := &dockertest.RunOptions{
 		Repository: defaultPostgresImage,
 		Tag:        defaultPostgresTag,
 		Env: []string{
-			"POSTGRES_PASSWORD=secret",
+			"POSTGRES_PASSWORD=" + defaultPostgresPassword,
 			"POSTGRES_DB=test",
 		},
 	}
@@ -158,12 +391,190 @@ func RunPostgresWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions)
 		opt(defaultRunOpts)
 	}
 
-	pass := internal.GetEnvValue(defaultRunOpts.Env, "POSTGRES_PASSWORD")
-	db := internal.GetEnvValue(defaultRunOpts.Env, "POSTGRES_DB")
+	// A RunOption that replaces Env, e.g. only to set POSTGRES_DB, keeps the default password
+	// unless it disables password authentication.
+	pass, ok := internal.LookupEnvValue(defaultRunOpts.Env, "POSTGRES_PASSWORD")
+	if !ok && internal.GetEnvValue(defaultRunOpts.Env, "POSTGRES_HOST_AUTH_METHOD") != "trust" {
+		pass = defaultPostgresPassword
+		defaultRunOpts.Env = append(defaultRunOpts.Env, "POSTGRES_PASSWORD="+pass)
+	}
+	// Without POSTGRES_DB, the image creates a database named after the user.
+	db := internal.GetEnvValueOr(defaultRunOpts.Env, "POSTGRES_DB", "postgres")
 
-	return RunDockerDB(t, defaultRunOpts, "5432/tcp", "postgres", func(actualPort string) string {
+	return defaultRunOpts, func(actualPort string) string {
 		return fmt.Sprintf("postgres://postgres:%s@%s/%s?sslmode=disable", pass, actualPort, db)
-	}, hostOpts...)
+	}
+}
+
+// EnablePostgresExtensions runs CREATE EXTENSION IF NOT EXISTS for each of the given extensions,
+// e.g. "uuid-ossp" or "pg_trgm". Call it before PrepDatabase when the schema depends on them.
+// The extensions must be available in the image: the base postgres image ships the contrib
+// extensions, while postgis requires an image such as "postgis/postgis".
+// If any extension cannot be created, it returns an error.
+func EnablePostgresExtensions(t testing.TB, db *sql.DB, names ...string) error {
+	t.Helper()
+
+	for _, name := range names {
+		stmt := fmt.Sprintf(`CREATE EXTENSION IF NOT EXISTS "%s"`, strings.ReplaceAll(name, `"`, `""`))
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to enable extension '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+const (
+	defaultClickHouseImage = "clickhouse/clickhouse-server"
+	defaultClickHouseTag   = "24.8"
+)
+
+// RunClickHouse starts a ClickHouse Docker container using the default settings and returns a connected *sql.DB
+// along with a cleanup function. It uses the default ClickHouse image ("clickhouse/clickhouse-server") with tag "24.8".
+// For more customization, use RunClickHouseWithOptions.
+func RunClickHouse(t testing.TB) (*sql.DB, func()) {
+	return RunClickHouseWithOptions(t, nil)
+}
+
+// RunClickHouseWithOptions starts a ClickHouse Docker container using Docker and returns a connected *sql.DB
+// along with a cleanup function. It applies the default settings:
+//   - Repository: "clickhouse/clickhouse-server"
+//   - Tag: "24.8"
+//   - Environment: CLICKHOUSE_USER=default, CLICKHOUSE_PASSWORD=secret, CLICKHOUSE_DB=test
+//
+// The connection uses the native protocol on 9000/tcp through the clickhouse-go driver.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+// The DSN is generated in the format:
+//
+//	"clickhouse://<CLICKHOUSE_USER>:<CLICKHOUSE_PASSWORD>@<actualPort>/<CLICKHOUSE_DB>".
+func RunClickHouseWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+	return RunClickHouseContext(context.Background(), t, runOpts, hostOpts...)
+}
+
+// RunClickHouseContext is like RunClickHouseWithOptions, but it aborts startup as soon as ctx is done.
+// See RunDockerDBContext.
+func RunClickHouseContext(ctx context.Context, t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+
+	opts, dsnFunc := clickHouseRunOptions(runOpts)
+	return RunDockerDBContext(ctx, t, opts, "9000/tcp", "clickhouse", dsnFunc, hostOpts...)
+}
+
+// TryRunClickHouse is like RunClickHouseWithOptions, but it returns an error instead of failing the test.
+// See TryRunDockerDB.
+func TryRunClickHouse(runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	return TryRunClickHouseContext(context.Background(), runOpts, hostOpts...)
+}
+
+// TryRunClickHouseContext is like TryRunClickHouse, but it aborts startup as soon as ctx is done.
+// See TryRunDockerDBContext.
+func TryRunClickHouseContext(ctx context.Context, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	opts, dsnFunc := clickHouseRunOptions(runOpts)
+	return TryRunDockerDBContext(ctx, opts, "9000/tcp", "clickhouse", dsnFunc, hostOpts...)
+}
+
+// clickHouseRunOptions builds the ClickHouse run options and the matching DSN function.
+func clickHouseRunOptions(runOpts []func(*dockertest.RunOptions)) (*dockertest.RunOptions, func(actualPort string) string) {
+	// Set default run options for ClickHouse.
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultClickHouseImage,
+		Tag:        defaultClickHouseTag,
+		Env: []string{
+			"CLICKHOUSE_USER=default",
+			"CLICKHOUSE_PASSWORD=secret",
+			"CLICKHOUSE_DB=test",
+		},
+	}
+
+	// Apply any provided RunOption functions to override defaults.
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	user := internal.GetEnvValue(defaultRunOpts.Env, "CLICKHOUSE_USER")
+	pass := internal.GetEnvValue(defaultRunOpts.Env, "CLICKHOUSE_PASSWORD")
+	db := internal.GetEnvValue(defaultRunOpts.Env, "CLICKHOUSE_DB")
+
+	// The ping only succeeds once CLICKHOUSE_DB exists, so the retry also covers the
+	// window in which the entrypoint is still creating the default databases.
+	return defaultRunOpts, func(actualPort string) string {
+		return fmt.Sprintf("clickhouse://%s:%s@%s/%s", user, pass, actualPort, db)
+	}
+}
+
+const (
+	defaultTimescaleDBImage = "timescale/timescaledb"
+	defaultTimescaleDBTag   = "2.17.2-pg16"
+)
+
+// RunTimescaleDB starts a TimescaleDB Docker container using the default settings and returns a connected *sql.DB
+// along with a cleanup function. It uses the default TimescaleDB image ("timescale/timescaledb") with tag "2.17.2-pg16".
+// For more customization, use RunTimescaleDBWithOptions.
+func RunTimescaleDB(t testing.TB) (*sql.DB, func()) {
+	return RunTimescaleDBWithOptions(t, nil)
+}
+
+// RunTimescaleDBWithOptions starts a TimescaleDB Docker container using Docker and returns a connected *sql.DB
+// along with a cleanup function. TimescaleDB is a PostgreSQL extension, so the container is configured
+// like RunPostgresWithOptions apart from the image:
+//   - Repository: "timescale/timescaledb"
+//   - Tag: "2.17.2-pg16"
+//   - Environment: POSTGRES_PASSWORD=secret, POSTGRES_DB=test
+//
+// The image preloads the timescaledb library and creates the extension in POSTGRES_DB, so hypertables
+// can be created right away (see CreateHypertable).
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunTimescaleDBWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+	return RunTimescaleDBContext(context.Background(), t, runOpts, hostOpts...)
+}
+
+// RunTimescaleDBContext is like RunTimescaleDBWithOptions, but it aborts startup as soon as ctx is done.
+// See RunDockerDBContext.
+func RunTimescaleDBContext(ctx context.Context, t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func()) {
+	t.Helper()
+
+	opts, dsnFunc := timescaleDBRunOptions(runOpts)
+	db, cleanup, err := runDockerDB(ctx, t.Logf, opts, "5432/tcp", "postgres", dsnFunc, postgresReady, hostOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, cleanup
+}
+
+// TryRunTimescaleDB is like RunTimescaleDBWithOptions, but it returns an error instead of failing the test.
+// See TryRunDockerDB.
+func TryRunTimescaleDB(runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	return TryRunTimescaleDBContext(context.Background(), runOpts, hostOpts...)
+}
+
+// TryRunTimescaleDBContext is like TryRunTimescaleDB, but it aborts startup as soon as ctx is done.
+// See TryRunDockerDBContext.
+func TryRunTimescaleDBContext(ctx context.Context, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sql.DB, func(), error) {
+	opts, dsnFunc := timescaleDBRunOptions(runOpts)
+	return runDockerDB(ctx, func(string, ...any) {}, opts, "5432/tcp", "postgres", dsnFunc, postgresReady, hostOpts...)
+}
+
+// timescaleDBRunOptions builds the TimescaleDB run options on top of the PostgreSQL ones.
+func timescaleDBRunOptions(runOpts []func(*dockertest.RunOptions)) (*dockertest.RunOptions, func(actualPort string) string) {
+	image := func(opts *dockertest.RunOptions) {
+		opts.Repository = defaultTimescaleDBImage
+		opts.Tag = defaultTimescaleDBTag
+	}
+	return postgresRunOptions(append([]func(*dockertest.RunOptions){image}, runOpts...))
+}
+
+// CreateHypertable turns an existing, empty table into a TimescaleDB hypertable partitioned by timeColumn.
+// If the hypertable cannot be created, it returns an error.
+func CreateHypertable(t testing.TB, db *sql.DB, table, timeColumn string) error {
+	t.Helper()
+
+	if _, err := db.Exec("SELECT create_hypertable($1::regclass, $2::name)", table, timeColumn); err != nil {
+		return fmt.Errorf("failed to create hypertable '%s': %w", table, err)
+	}
+	return nil
 }
 
 // InitialDBSetup is used to set up the database before tests.
@@ -172,37 +583,75 @@ func RunPostgresWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions)
 type InitialDBSetup struct {
 	// SchemaSQL contains DDL statements (e.g., table or index creation).
 	SchemaSQL string
+	// SplitStatements splits SchemaSQL into individual statements and executes them one by one.
+	// Enable it for drivers that reject several statements in a single Exec, such as MySQL without
+	// multiStatements. String literals, comments, Postgres $$-quoted bodies and MySQL DELIMITER
+	// lines are respected. The quoting rules follow Dialect; with DialectPostgres, a backslash only
+	// escapes inside E'...' literals, as with standard_conforming_strings=on, even on a database that
+	// turns the setting off.
+	SplitStatements bool
+	// Dialect selects the quoting rules for SplitStatements. The zero value, DialectAuto, picks
+	// them from db's driver.
+	Dialect Dialect
 	// InitialData contains SQL statements for seeding initial data.
 	InitialData []string
 }
 
 // PrepDatabase executes the provided schema and initial data SQL statements sequentially
-// to prepare the test database. It returns an error if any step fails.
+// to prepare the test database. It returns an error if any step fails. The error names the
+// zero-based index of the failing setup and, for InitialData, of the failing statement along
+// with a short preview of it.
 func PrepDatabase(t testing.TB, db *sql.DB, setups ...InitialDBSetup) error {
 	t.Helper()
 
-	for _, setup := range setups {
-		if setup.SchemaSQL != "" {
-			if _, err := db.Exec(setup.SchemaSQL); err != nil {
-				return fmt.Errorf("failed to execute schema SQL: %w", err)
-			}
+	for i, setup := range setups {
+		if err := prepSetup(db, setup); err != nil {
+			return fmt.Errorf("setup %d: %w", i, err)
 		}
-		// Execute the initial data insertion (DML) within a transaction.
-		if len(setup.InitialData) > 0 {
-			tx, err := db.Begin()
-			if err != nil {
-				return fmt.Errorf("failed to begin transaction: %w", err)
-			}
-			for _, stmt := range setup.InitialData {
-				if _, err := tx.Exec(stmt); err != nil {
-					_ = tx.Rollback()
-					return fmt.Errorf("failed to execute initial data SQL: %w", err)
+	}
+	return nil
+}
+
+// prepSetup executes a single InitialDBSetup.
+func prepSetup(db *sql.DB, setup InitialDBSetup) error {
+	if setup.SchemaSQL != "" {
+		if setup.SplitStatements {
+			for i, stmt := range splitStatements(setup.SchemaSQL, dialectOf(db, setup.Dialect)) {
+				if _, err := db.Exec(stmt); err != nil {
+					return fmt.Errorf("failed to execute schema SQL statement %d (%s): %w", i, previewSQL(stmt), err)
 				}
 			}
-			if err := tx.Commit(); err != nil {
-				return fmt.Errorf("failed to commit transaction: %w", err)
+		} else if _, err := db.Exec(setup.SchemaSQL); err != nil {
+			return fmt.Errorf("failed to execute schema SQL: %w", err)
+		}
+	}
+	// Execute the initial data insertion (DML) within a transaction.
+	if len(setup.InitialData) > 0 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		for i, stmt := range setup.InitialData {
+			if _, err := tx.Exec(stmt); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("failed to execute initial data SQL at index %d (%s): %w", i, previewSQL(stmt), err)
 			}
 		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
 	}
 	return nil
 }
+
+// maxPreviewLen is the maximum number of characters of a statement included in error messages.
+const maxPreviewLen = 60
+
+// previewSQL returns stmt with its whitespace collapsed, quoted and truncated to maxPreviewLen characters.
+func previewSQL(stmt string) string {
+	preview := strings.Join(strings.Fields(stmt), " ")
+	if r := []rune(preview); len(r) > maxPreviewLen {
+		preview = string(r[:maxPreviewLen]) + "..."
+	}
+	return fmt.Sprintf("%q", preview)
+}
@@ -1,10 +1,31 @@
 package sql_test
 
 import (
+	"bytes"
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/cenkalti/backoff/v4"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx"
 	"github.com/vvatanabe/dockertestx/sql"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestDefaultMySQL demonstrates using RunMySQL with default options.
@@ -178,3 +199,1073 @@ func TestPostgresWithCustomHostOptions(t *testing.T) {
 		t.Errorf("expected quantity 10, but got %d", quantity)
 	}
 }
+
+// TestEnablePostgresExtensions demonstrates enabling pg_trgm before using trigram similarity.
+func TestEnablePostgresExtensions(t *testing.T) {
+	db, cleanup := sql.RunPostgres(t)
+	defer cleanup()
+
+	if err := sql.EnablePostgresExtensions(t, db, "pg_trgm", "uuid-ossp"); err != nil {
+		t.Fatalf("EnablePostgresExtensions failed: %v", err)
+	}
+	// Enabling an extension twice is a no-op.
+	if err := sql.EnablePostgresExtensions(t, db, "pg_trgm"); err != nil {
+		t.Fatalf("EnablePostgresExtensions failed on second call: %v", err)
+	}
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL: `CREATE TABLE words (id UUID PRIMARY KEY DEFAULT uuid_generate_v4(), word TEXT NOT NULL)`,
+		InitialData: []string{
+			`INSERT INTO words (word) VALUES ('container')`,
+			`INSERT INTO words (word) VALUES ('database')`,
+		},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	var word string
+	var score float64
+	err := db.QueryRow(
+		"SELECT word, similarity(word, $1) AS score FROM words ORDER BY score DESC LIMIT 1", "contianer",
+	).Scan(&word, &score)
+	if err != nil {
+		t.Fatalf("failed to run similarity query: %v", err)
+	}
+	if word != "container" {
+		t.Errorf("expected closest word 'container', but got '%s'", word)
+	}
+	if score <= 0 {
+		t.Errorf("expected a positive similarity score, but got %f", score)
+	}
+}
+
+// TestDefaultClickHouse demonstrates using RunClickHouse with a MergeTree table.
+func TestDefaultClickHouse(t *testing.T) {
+	// Start a ClickHouse container with default options.
+	db, cleanup := sql.RunClickHouse(t)
+	defer cleanup()
+
+	// Schema SQL for creating a MergeTree table in ClickHouse.
+	schema := `
+	CREATE TABLE IF NOT EXISTS events (
+		id UInt64,
+		name String
+	) ENGINE = MergeTree()
+	ORDER BY id
+	`
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL: schema,
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	// Insert rows outside a transaction, since ClickHouse has no transactional DML.
+	if _, err := db.Exec(`INSERT INTO events (id, name) VALUES (1, 'signup'), (2, 'login'), (3, 'logout')`); err != nil {
+		t.Fatalf("failed to insert rows: %v", err)
+	}
+
+	var count uint64
+	if err := db.QueryRow("SELECT count() FROM events").Scan(&count); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 rows, but got %d", count)
+	}
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM events WHERE id = ?", 2).Scan(&name); err != nil {
+		t.Fatalf("failed to retrieve data: %v", err)
+	}
+	if name != "login" {
+		t.Errorf("expected name 'login', but got '%s'", name)
+	}
+}
+
+// TestTryRunMySQL demonstrates starting MySQL without failing the test on error.
+func TestTryRunMySQL(t *testing.T) {
+	db, cleanup, err := sql.TryRunMySQL(nil)
+	if err != nil {
+		t.Fatalf("TryRunMySQL failed: %v", err)
+	}
+	defer cleanup()
+
+	if err := db.Ping(); err != nil {
+		t.Errorf("failed to ping database: %v", err)
+	}
+}
+
+// TestTryRunDockerDBReturnsError verifies that a container that cannot be started is reported as an error.
+func TestTryRunDockerDBReturnsError(t *testing.T) {
+	runOpts := &dockertest.RunOptions{
+		Repository: "dockertestx/does-not-exist",
+		Tag:        "never",
+	}
+	db, cleanup, err := sql.TryRunDockerDB(runOpts, "5432/tcp", "postgres", func(actualPort string) string {
+		return ""
+	})
+	if err == nil {
+		cleanup()
+		t.Fatal("expected an error, got nil")
+	}
+	if db != nil || cleanup != nil {
+		t.Error("expected no database and no cleanup function on error")
+	}
+}
+
+// TestTryRunMySQLContextCanceled verifies that cancelling the context aborts startup promptly
+// and leaves no container behind.
+func TestTryRunMySQLContextCanceled(t *testing.T) {
+	label := fmt.Sprintf("dockertestx-cancel-%d", time.Now().UnixNano())
+	withLabel := func(opts *dockertest.RunOptions) {
+		opts.Labels = map[string]string{"dockertestx.test": label}
+	}
+
+	// MySQL needs far longer than this to accept connections
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	db, cleanup, err := sql.TryRunMySQLContext(ctx, []func(*dockertest.RunOptions){withLabel})
+	if err == nil {
+		cleanup()
+		t.Fatal("expected an error, got nil")
+	}
+	if db != nil {
+		t.Error("expected no database on error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a context error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Errorf("expected startup to abort promptly, took %s", elapsed)
+	}
+
+	// No container with our label may be left behind
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %v", err)
+	}
+	containers, err := pool.Client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {"dockertestx.test=" + label}},
+	})
+	if err != nil {
+		t.Fatalf("failed to list containers: %v", err)
+	}
+	if len(containers) != 0 {
+		t.Errorf("expected no leaked containers, found %d", len(containers))
+	}
+}
+
+// TestSplitStatements verifies that statement splitting respects literals, comments and bodies.
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		postgres bool
+		want     []string
+	}{
+		{
+			name:   "simple statements",
+			script: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:   "semicolons in string literals",
+			script: `INSERT INTO t VALUES ('a;b'); INSERT INTO t VALUES ('it''s;'); INSERT INTO t VALUES ("x;y"), ('c\';d')`,
+			want: []string{
+				`INSERT INTO t VALUES ('a;b')`,
+				`INSERT INTO t VALUES ('it''s;')`,
+				`INSERT INTO t VALUES ("x;y"), ('c\';d')`,
+			},
+		},
+		{
+			name:   "comments",
+			script: "-- create a table; really\nCREATE TABLE a (id INT); /* block; comment */ CREATE TABLE b (id INT) /*!50100 ENGINE=InnoDB */;",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT) /*!50100 ENGINE=InnoDB */"},
+		},
+		{
+			name:     "postgres function body",
+			postgres: true,
+			script: `CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+	NEW.updated_at := now();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE FUNCTION one() RETURNS int AS $fn$ SELECT 1; $fn$ LANGUAGE sql;
+SELECT $1;`,
+			want: []string{
+				"CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n\tNEW.updated_at := now();\n\tRETURN NEW;\nEND;\n$$ LANGUAGE plpgsql",
+				"CREATE FUNCTION one() RETURNS int AS $fn$ SELECT 1; $fn$ LANGUAGE sql",
+				"SELECT $1",
+			},
+		},
+		{
+			name: "mysql stored procedure with DELIMITER",
+			script: `CREATE TABLE counters (n INT);
+DELIMITER //
+CREATE PROCEDURE bump()
+BEGIN
+	UPDATE counters SET n = n + 1;
+	SELECT n FROM counters;
+END //
+DELIMITER ;
+INSERT INTO counters VALUES (0);`,
+			want: []string{
+				"CREATE TABLE counters (n INT)",
+				"CREATE PROCEDURE bump()\nBEGIN\n\tUPDATE counters SET n = n + 1;\n\tSELECT n FROM counters;\nEND",
+				"INSERT INTO counters VALUES (0)",
+			},
+		},
+		{
+			name:     "postgres backslash in standard string",
+			script:   `INSERT INTO p VALUES ('C:\'); INSERT INTO p VALUES ('x');`,
+			postgres: true,
+			want: []string{
+				`INSERT INTO p VALUES ('C:\')`,
+				`INSERT INTO p VALUES ('x')`,
+			},
+		},
+		{
+			name:     "postgres escape string",
+			script:   `INSERT INTO p VALUES (E'a\';b'), (e'\\'); INSERT INTO p VALUES (name'x\');`,
+			postgres: true,
+			want: []string{
+				`INSERT INTO p VALUES (E'a\';b'), (e'\\')`,
+				`INSERT INTO p VALUES (name'x\')`,
+			},
+		},
+		{
+			name:   "mysql dollar signs in identifiers",
+			script: "CREATE TABLE a$x$b (id INT); INSERT INTO a$x$b VALUES (1);",
+			want: []string{
+				"CREATE TABLE a$x$b (id INT)",
+				"INSERT INTO a$x$b VALUES (1)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialect := sql.DialectMySQL
+			if tt.postgres {
+				dialect = sql.DialectPostgres
+			}
+			got := sql.SplitStatements(tt.script, dialect)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d statements, got %d: %q", len(tt.want), len(got), got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("statement %d: expected %q, got %q", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	tests := []struct {
+		driver  string
+		dialect sql.Dialect
+		want    sql.Dialect
+	}{
+		{driver: "postgres", want: sql.DialectPostgres},
+		{driver: "pgx", want: sql.DialectPostgres},
+		{driver: "mysql", want: sql.DialectMySQL},
+		{driver: "mysql", dialect: sql.DialectPostgres, want: sql.DialectPostgres},
+	}
+	for _, tt := range tests {
+		// Opening does not connect, so no server is needed.
+		db, err := stdsql.Open(tt.driver, "")
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", tt.driver, err)
+		}
+		if got := sql.DialectOf(db, tt.dialect); got != tt.want {
+			t.Errorf("DialectOf(%s, %v) = %v, want %v", tt.driver, tt.dialect, got, tt.want)
+		}
+		_ = db.Close()
+	}
+}
+
+// TestMySQLSplitStatements demonstrates running a multi-statement schema with a stored procedure on MySQL.
+func TestMySQLSplitStatements(t *testing.T) {
+	db, cleanup := sql.RunMySQL(t)
+	defer cleanup()
+
+	schema := `
+	CREATE TABLE notes (id INT PRIMARY KEY, body VARCHAR(255) NOT NULL);
+	INSERT INTO notes VALUES (1, 'first; with a semicolon');
+	DELIMITER //
+	CREATE PROCEDURE add_note(IN note_id INT, IN note_body VARCHAR(255))
+	BEGIN
+		INSERT INTO notes VALUES (note_id, note_body);
+		UPDATE notes SET body = CONCAT(body, '!') WHERE id = note_id;
+	END //
+	DELIMITER ;
+	CALL add_note(2, 'second; also');
+	`
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL:       schema,
+		SplitStatements: true,
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	var body string
+	if err := db.QueryRow("SELECT body FROM notes WHERE id = ?", 2).Scan(&body); err != nil {
+		t.Fatalf("failed to retrieve data: %v", err)
+	}
+	if body != "second; also!" {
+		t.Errorf("expected body 'second; also!', but got '%s'", body)
+	}
+}
+
+// failingDriver is a database/sql driver whose statements fail when they contain "FAIL".
+// It lets PrepDatabase error reporting be tested without a container.
+type failingDriver struct{}
+
+func (failingDriver) Open(string) (driver.Conn, error) { return failingConn{}, nil }
+
+type failingConn struct{}
+
+func (failingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+func (failingConn) Close() error              { return nil }
+func (failingConn) Begin() (driver.Tx, error) { return failingConn{}, nil }
+func (failingConn) Commit() error             { return nil }
+func (failingConn) Rollback() error           { return nil }
+
+func (failingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "FAIL") {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func init() {
+	stdsql.Register("dockertestx-failing", failingDriver{})
+}
+
+// TestPrepDatabaseReportsFailingIndex verifies that the error names the failing setup and statement.
+func TestPrepDatabaseReportsFailingIndex(t *testing.T) {
+	db, err := stdsql.Open("dockertestx-failing", "")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	err = sql.PrepDatabase(t, db,
+		sql.InitialDBSetup{
+			InitialData: []string{"INSERT INTO a VALUES (1)"},
+		},
+		sql.InitialDBSetup{
+			InitialData: []string{
+				"INSERT INTO b VALUES (1)",
+				"INSERT INTO b VALUES (2)",
+				"INSERT INTO b VALUES (FAIL, 'a very long value that makes the statement exceed the preview length')",
+				"INSERT INTO b VALUES (4)",
+			},
+		},
+	)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{"setup 1", "index 2", `"INSERT INTO b VALUES (FAIL,`, "...", "syntax error"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected error to contain %q, got: %s", want, msg)
+		}
+	}
+}
+
+// TestMySQLWithCharset demonstrates storing and reading back an emoji with utf8mb4.
+func TestMySQLWithCharset(t *testing.T) {
+	db, cleanup := sql.RunMySQLWithOptions(t, []func(*dockertest.RunOptions){
+		sql.WithMySQLCharset("utf8mb4", "utf8mb4_unicode_ci"),
+	})
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL: `CREATE TABLE messages (id INT PRIMARY KEY, body VARCHAR(255) NOT NULL)`,
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	want := "hello 🍣🍺 world"
+	if _, err := db.Exec("INSERT INTO messages (id, body) VALUES (?, ?)", 1, want); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+
+	var got string
+	if err := db.QueryRow("SELECT body FROM messages WHERE id = ?", 1).Scan(&got); err != nil {
+		t.Fatalf("failed to retrieve data: %v", err)
+	}
+	if got != want {
+		t.Errorf("expected body '%s', but got '%s'", want, got)
+	}
+
+	var collation string
+	if err := db.QueryRow("SELECT @@collation_server").Scan(&collation); err != nil {
+		t.Fatalf("failed to query server collation: %v", err)
+	}
+	if collation != "utf8mb4_unicode_ci" {
+		t.Errorf("expected server collation 'utf8mb4_unicode_ci', but got '%s'", collation)
+	}
+}
+
+func TestEscapeMySQLString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"secret", "secret"},
+		{"it's", "it''s"},
+		{`back\slash'`, `back\\slash''`},
+	}
+	for _, tt := range tests {
+		if got := sql.EscapeMySQLString(tt.in); got != tt.want {
+			t.Errorf("EscapeMySQLString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestMySQLBinlog reads the row event of an INSERT from the binlog, as a CDC consumer would.
+func TestMySQLBinlog(t *testing.T) {
+	db, cleanup := sql.RunMySQLWithOptions(t, []func(*dockertest.RunOptions){
+		sql.WithMySQLBinlog(),
+	})
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL: `CREATE TABLE events (id INT PRIMARY KEY, name VARCHAR(50) NOT NULL)`,
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	cfg := sql.MySQLConfig(t, db)
+	host, portStr, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		t.Fatalf("failed to parse address '%s': %v", cfg.Addr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		t.Fatalf("failed to parse port '%s': %v", portStr, err)
+	}
+
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID: 100,
+		Flavor:   gomysql.MySQLFlavor,
+		Host:     host,
+		Port:     uint16(port),
+		User:     cfg.User,
+		Password: cfg.Passwd,
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	defer syncer.Close()
+
+	gset, err := gomysql.ParseGTIDSet(gomysql.MySQLFlavor, "")
+	if err != nil {
+		t.Fatalf("failed to parse GTID set: %v", err)
+	}
+	streamer, err := syncer.StartSyncGTID(gset)
+	if err != nil {
+		t.Fatalf("failed to start binlog sync: %v", err)
+	}
+
+	if _, err := db.Exec("INSERT INTO events (id, name) VALUES (?, ?)", 1, "created"); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for {
+		ev, err := streamer.GetEvent(ctx)
+		if err != nil {
+			t.Fatalf("no row event for the insert: %v", err)
+		}
+		rows, ok := ev.Event.(*replication.RowsEvent)
+		if !ok || ev.Header.EventType != replication.WRITE_ROWS_EVENTv2 || string(rows.Table.Table) != "events" {
+			continue
+		}
+		if len(rows.Rows) != 1 || fmt.Sprint(rows.Rows[0]) != "[1 created]" {
+			t.Errorf("expected row [1 created], got %v", rows.Rows)
+		}
+		return
+	}
+}
+
+func TestMySQLPrimaryReplica(t *testing.T) {
+	primary, replica, cleanup := sql.RunMySQLPrimaryReplica(t)
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, primary, sql.InitialDBSetup{
+		SchemaSQL:   `CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50) NOT NULL)`,
+		InitialData: []string{`INSERT INTO users (id, name) VALUES (1, 'Alice')`},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	// Replication is asynchronous, so poll the replica until the row shows up.
+	var name string
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		err := replica.QueryRow("SELECT name FROM users WHERE id = ?", 1).Scan(&name)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("row did not replicate within 30s: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	if name != "Alice" {
+		t.Errorf("expected name 'Alice' on the replica, but got '%s'", name)
+	}
+}
+
+func TestDumpPostgres(t *testing.T) {
+	var resource *dockertest.Resource
+	db, cleanup := sql.RunPostgresWithOptions(t, []func(*dockertest.RunOptions){
+		dockertestx.WithResource(&resource),
+	})
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL:   `CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50) NOT NULL)`,
+		InitialData: []string{`INSERT INTO users (id, name) VALUES (1, 'Alice')`},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "dump.sql")
+	if err := sql.DumpPostgres(t, resource, out); err != nil {
+		t.Fatalf("DumpPostgres failed: %v", err)
+	}
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read dump: %v", err)
+	}
+	for _, want := range []string{
+		"CREATE TABLE public.users",
+		"INSERT INTO public.users VALUES (1, 'Alice');",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected dump to contain %q, but got:\n%s", want, b)
+		}
+	}
+}
+
+// TestRestorePostgresDump dumps a seeded database in plain and custom format and restores each dump
+// into a fresh container.
+func TestRestorePostgresDump(t *testing.T) {
+	var source *dockertest.Resource
+	db, cleanup := sql.RunPostgresWithOptions(t, []func(*dockertest.RunOptions){
+		dockertestx.WithResource(&source),
+	})
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL: `CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50) NOT NULL)`,
+		InitialData: []string{
+			`INSERT INTO users (id, name) VALUES (1, 'Alice')`,
+			`INSERT INTO users (id, name) VALUES (2, 'Bob; Jr.')`,
+		},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "dump.sql")
+	if err := sql.DumpPostgres(t, source, plain); err != nil {
+		t.Fatalf("DumpPostgres failed: %v", err)
+	}
+	var custom bytes.Buffer
+	if code, err := source.Exec([]string{"pg_dump", "--username", "postgres", "--format", "custom", "test"}, dockertest.ExecOptions{
+		StdOut: &custom,
+	}); err != nil || code != 0 {
+		t.Fatalf("failed to write custom-format dump (exit code %d): %v", code, err)
+	}
+	customPath := filepath.Join(dir, "dump.custom")
+	if err := os.WriteFile(customPath, custom.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write custom-format dump: %v", err)
+	}
+
+	want := queryUsers(t, db)
+	for _, dumpPath := range []string{plain, customPath} {
+		t.Run(filepath.Base(dumpPath), func(t *testing.T) {
+			var target *dockertest.Resource
+			restored, cleanup := sql.RunPostgresWithOptions(t, []func(*dockertest.RunOptions){
+				dockertestx.WithResource(&target),
+			})
+			defer cleanup()
+
+			if err := sql.RestorePostgresDump(t, target, dumpPath); err != nil {
+				t.Fatalf("RestorePostgresDump failed: %v", err)
+			}
+			if got := queryUsers(t, restored); !slices.Equal(got, want) {
+				t.Errorf("expected restored users %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+// queryUsers returns the rows of the users table as "id:name" strings ordered by id.
+func queryUsers(t *testing.T, db *stdsql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT id, name FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("failed to query users: %v", err)
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("failed to scan user: %v", err)
+		}
+		users = append(users, fmt.Sprintf("%d:%s", id, name))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to read users: %v", err)
+	}
+	return users
+}
+
+func TestDumpMySQL(t *testing.T) {
+	var resource *dockertest.Resource
+	db, cleanup := sql.RunMySQLWithOptions(t, []func(*dockertest.RunOptions){
+		dockertestx.WithResource(&resource),
+	})
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL:   `CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50) NOT NULL)`,
+		InitialData: []string{`INSERT INTO users (id, name) VALUES (1, 'Alice')`},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "dump.sql")
+	if err := sql.DumpMySQL(t, resource, out); err != nil {
+		t.Fatalf("DumpMySQL failed: %v", err)
+	}
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read dump: %v", err)
+	}
+	for _, want := range []string{
+		"CREATE TABLE `users`",
+		"INSERT INTO `users` VALUES (1,'Alice');",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected dump to contain %q, but got:\n%s", want, b)
+		}
+	}
+}
+
+func TestNewPostgresSchema(t *testing.T) {
+	db, cleanup := sql.RunPostgres(t)
+	defer cleanup()
+
+	// The group only returns once its parallel subtests have finished, so the container
+	// outlives them.
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"first", "second"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				schemaDB, dropSchema := sql.NewPostgresSchema(t, db)
+				defer dropSchema()
+
+				// Both subtests create a table with the same name.
+				if err := sql.PrepDatabase(t, schemaDB, sql.InitialDBSetup{
+					SchemaSQL:   `CREATE TABLE items (name TEXT NOT NULL)`,
+					InitialData: []string{fmt.Sprintf(`INSERT INTO items (name) VALUES ('%s')`, name)},
+				}); err != nil {
+					t.Fatalf("PrepDatabase failed: %v", err)
+				}
+
+				var names []string
+				rows, err := schemaDB.Query("SELECT name FROM items")
+				if err != nil {
+					t.Fatalf("failed to query items: %v", err)
+				}
+				defer rows.Close()
+				for rows.Next() {
+					var n string
+					if err := rows.Scan(&n); err != nil {
+						t.Fatalf("failed to scan item: %v", err)
+					}
+					names = append(names, n)
+				}
+				if err := rows.Err(); err != nil {
+					t.Fatalf("failed to iterate items: %v", err)
+				}
+				if len(names) != 1 || names[0] != name {
+					t.Errorf("expected only item '%s', but got %v", name, names)
+				}
+			})
+		}
+	})
+
+	// The schemas are dropped again, so the shared database has no items table.
+	var exists bool
+	if err := db.QueryRow("SELECT to_regclass('items') IS NOT NULL").Scan(&exists); err != nil {
+		t.Fatalf("failed to look up items table: %v", err)
+	}
+	if exists {
+		t.Error("expected no items table in the shared schema")
+	}
+}
+
+func TestTimescaleDB(t *testing.T) {
+	db, cleanup := sql.RunTimescaleDB(t)
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL: `CREATE TABLE metrics (time TIMESTAMPTZ NOT NULL, value DOUBLE PRECISION NOT NULL)`,
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+	if err := sql.CreateHypertable(t, db, "metrics", "time"); err != nil {
+		t.Fatalf("CreateHypertable failed: %v", err)
+	}
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		InitialData: []string{
+			`INSERT INTO metrics (time, value) VALUES ('2024-01-01 00:10:00+00', 1)`,
+			`INSERT INTO metrics (time, value) VALUES ('2024-01-01 00:50:00+00', 3)`,
+			`INSERT INTO metrics (time, value) VALUES ('2024-01-01 01:20:00+00', 10)`,
+		},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT time_bucket('1 hour', time) AS bucket, avg(value) FROM metrics GROUP BY bucket ORDER BY bucket`)
+	if err != nil {
+		t.Fatalf("failed to run time_bucket query: %v", err)
+	}
+	defer rows.Close()
+
+	var avgs []float64
+	for rows.Next() {
+		var bucket time.Time
+		var avg float64
+		if err := rows.Scan(&bucket, &avg); err != nil {
+			t.Fatalf("failed to scan bucket: %v", err)
+		}
+		avgs = append(avgs, avg)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to iterate buckets: %v", err)
+	}
+	if len(avgs) != 2 || avgs[0] != 2 || avgs[1] != 10 {
+		t.Errorf("expected hourly averages [2 10], but got %v", avgs)
+	}
+}
+
+func TestRunMySQLX(t *testing.T) {
+	db, cleanup := sql.RunMySQLX(t)
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db.DB, sql.InitialDBSetup{
+		SchemaSQL: `CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50) NOT NULL, email VARCHAR(255) NOT NULL)`,
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	type user struct {
+		ID    int    `db:"id"`
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+	want := user{ID: 1, Name: "Alice", Email: "alice@example.com"}
+	if _, err := db.NamedExec("INSERT INTO users (id, name, email) VALUES (:id, :name, :email)", want); err != nil {
+		t.Fatalf("failed to insert user: %v", err)
+	}
+
+	var got user
+	if err := db.Get(&got, "SELECT id, name, email FROM users WHERE id = ?", 1); err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %+v, but got %+v", want, got)
+	}
+}
+
+func TestRunPostgresX(t *testing.T) {
+	db, cleanup := sql.RunPostgresX(t)
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db.DB, sql.InitialDBSetup{
+		SchemaSQL:   `CREATE TABLE users (id INT PRIMARY KEY, name TEXT NOT NULL)`,
+		InitialData: []string{`INSERT INTO users (id, name) VALUES (1, 'Bob')`},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	var got struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	if err := db.Get(&got, db.Rebind("SELECT id, name FROM users WHERE id = ?"), 1); err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if got.Name != "Bob" {
+		t.Errorf("expected name 'Bob', but got '%s'", got.Name)
+	}
+}
+
+// startingDriver is a database/sql driver that imitates a PostgreSQL server: connections succeed,
+// but queries fail with the error stored in startupErr until it is cleared.
+type startingDriver struct{}
+
+var startupErr error
+
+func (startingDriver) Open(string) (driver.Conn, error) { return startingConn{}, nil }
+
+type startingConn struct{ failingConn }
+
+func (startingConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if startupErr != nil {
+		return nil, startupErr
+	}
+	return &oneRow{}, nil
+}
+
+// oneRow is a result set with a single row holding the integer 1.
+type oneRow struct{ done bool }
+
+func (*oneRow) Columns() []string { return []string{"?column?"} }
+func (*oneRow) Close() error      { return nil }
+func (r *oneRow) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func init() {
+	stdsql.Register("dockertestx-starting", startingDriver{})
+}
+
+// TestPostgresReadyDuringStartup verifies that a server which accepts connections but still reports
+// cannot_connect_now is not considered ready, while authentication failures end the retry.
+func TestPostgresReadyDuringStartup(t *testing.T) {
+	db, err := stdsql.Open("dockertestx-starting", "")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	defer func() { startupErr = nil }()
+	ctx := context.Background()
+
+	// Test case 1: The server is starting up, so readiness must be retried.
+	startupErr = &pq.Error{Code: "57P03", Message: "the database system is starting up"}
+	err = sql.PostgresReady(ctx, db)
+	if err == nil {
+		t.Fatal("expected an error while the server is starting up")
+	}
+	var permanent *backoff.PermanentError
+	if errors.As(err, &permanent) {
+		t.Errorf("expected a retryable error, but got a permanent one: %v", err)
+	}
+
+	// Test case 2: Authentication failures cannot resolve themselves.
+	startupErr = &pq.Error{Code: "28P01", Message: "password authentication failed"}
+	if err := sql.PostgresReady(ctx, db); !errors.As(err, &permanent) {
+		t.Errorf("expected a permanent error, but got %v", err)
+	}
+
+	// Test case 3: Once the server answers queries, it is ready.
+	startupErr = nil
+	if err := sql.PostgresReady(ctx, db); err != nil {
+		t.Errorf("expected the server to be ready, but got %v", err)
+	}
+}
+
+func TestSeedJSON(t *testing.T) {
+	db, cleanup := sql.RunPostgres(t)
+	defer cleanup()
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL: `CREATE TABLE users (
+			id INT PRIMARY KEY,
+			name TEXT NOT NULL,
+			nickname TEXT,
+			score DOUBLE PRECISION,
+			active BOOLEAN,
+			tags JSONB
+		)`,
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join("testdata", "users.json"))
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer f.Close()
+	if err := sql.SeedJSON(t, db, "users", f); err != nil {
+		t.Fatalf("SeedJSON failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("failed to count users: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 users, but got %d", count)
+	}
+
+	var name string
+	var score stdsql.NullFloat64
+	var active bool
+	var firstTag string
+	if err := db.QueryRow("SELECT name, score, active, tags->>0 FROM users WHERE id = 1").Scan(&name, &score, &active, &firstTag); err != nil {
+		t.Fatalf("failed to retrieve user 1: %v", err)
+	}
+	if name != "Alice" || score.Float64 != 9.5 || !active || firstTag != "admin" {
+		t.Errorf("unexpected user 1: name=%s score=%v active=%t firstTag=%s", name, score, active, firstTag)
+	}
+
+	// Explicit nulls and missing keys are both inserted as NULL.
+	var nickname stdsql.NullString
+	if err := db.QueryRow("SELECT score, nickname FROM users WHERE id = 2").Scan(&score, &nickname); err != nil {
+		t.Fatalf("failed to retrieve user 2: %v", err)
+	}
+	if score.Valid || nickname.Valid {
+		t.Errorf("expected NULL score and nickname for user 2, but got %v and %v", score, nickname)
+	}
+}
+
+func TestExecSQLFile(t *testing.T) {
+	// MySQL rejects several statements in a single Exec, so the script is split
+	db, cleanup := sql.RunMySQL(t)
+	defer cleanup()
+
+	if err := sql.ExecSQLFile(t, db, filepath.Join("testdata", "create_items.sql"), sql.WithSplitStatements()); err != nil {
+		t.Fatalf("ExecSQLFile failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil {
+		t.Fatalf("failed to count items: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 items, got %d", count)
+	}
+
+	if err := sql.ExecSQLFile(t, db, filepath.Join("testdata", "drop_items.sql")); err != nil {
+		t.Fatalf("ExecSQLFile failed: %v", err)
+	}
+	if _, err := db.Exec("SELECT COUNT(*) FROM items"); err == nil {
+		t.Error("expected the items table to be dropped")
+	}
+
+	if err := sql.ExecSQLReader(t, db, strings.NewReader("SELECT * FROM missing_table")); err == nil {
+		t.Error("expected an error for a failing script")
+	}
+}
+
+// TestCockroachCluster starts a three-node cluster, writes a row and reads it back.
+func TestCockroachCluster(t *testing.T) {
+	db, cleanup := sql.RunCockroachCluster(t, 3)
+	defer cleanup()
+
+	var nodes int
+	if err := db.QueryRow("SELECT count(*) FROM crdb_internal.gossip_nodes WHERE is_live").Scan(&nodes); err != nil {
+		t.Fatalf("failed to count live nodes: %v", err)
+	}
+	if nodes != 3 {
+		t.Errorf("expected 3 live nodes, got %d", nodes)
+	}
+
+	if err := sql.PrepDatabase(t, db, sql.InitialDBSetup{
+		SchemaSQL:   "CREATE TABLE accounts (id INT PRIMARY KEY, balance INT NOT NULL)",
+		InitialData: []string{"INSERT INTO accounts (id, balance) VALUES (1, 100), (2, 250)"},
+	}); err != nil {
+		t.Fatalf("PrepDatabase failed: %v", err)
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT sum(balance) FROM accounts").Scan(&total); err != nil {
+		t.Fatalf("failed to read accounts: %v", err)
+	}
+	if total != 350 {
+		t.Errorf("expected a total balance of 350, got %d", total)
+	}
+}
+
+// TestWithDBPool limits the pool to a single connection and verifies that concurrent queries wait
+// for it instead of failing.
+func TestWithDBPool(t *testing.T) {
+	db, cleanup := sql.RunPostgresWithOptions(t, []func(*dockertest.RunOptions){
+		sql.WithDBPool(1, 1, time.Minute),
+	})
+	defer cleanup()
+
+	if got := db.Stats().MaxOpenConnections; got != 1 {
+		t.Fatalf("expected MaxOpenConnections 1, got %d", got)
+	}
+
+	const queries = 4
+	const sleep = 200 * time.Millisecond
+	errs := make(chan error, queries)
+	start := time.Now()
+	for i := 0; i < queries; i++ {
+		go func() {
+			_, err := db.Exec("SELECT pg_sleep($1)", sleep.Seconds())
+			errs <- err
+		}()
+	}
+	for i := 0; i < queries; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent query failed: %v", err)
+		}
+	}
+
+	// With a single connection, the queries run one after another.
+	if elapsed := time.Since(start); elapsed < queries*sleep {
+		t.Errorf("expected the queries to be serialized (at least %s), took %s", queries*sleep, elapsed)
+	}
+	if got := db.Stats().WaitCount; got == 0 {
+		t.Error("expected queries to wait for the connection")
+	}
+}
+
+// TestMySQLRunOptionsUnquotedEnv checks that quoted credentials are kept verbatim by default and
+// reach the container and the DSN without their quotes with dockertestx.WithUnquotedEnv.
+func TestMySQLRunOptionsUnquotedEnv(t *testing.T) {
+	quoted := func(opts *dockertest.RunOptions) {
+		opts.Env = []string{`MYSQL_ROOT_PASSWORD="p@ss=word"`, "MYSQL_DATABASE='app'"}
+	}
+
+	opts, dsnFunc := sql.MySQLRunOptions([]func(*dockertest.RunOptions){quoted})
+	if !slices.Equal(opts.Env, []string{`MYSQL_ROOT_PASSWORD="p@ss=word"`, "MYSQL_DATABASE='app'"}) {
+		t.Errorf("expected the env to be kept verbatim, but got %v", opts.Env)
+	}
+	if got, want := dsnFunc("localhost:3306"), `root:"p@ss=word"@tcp(localhost:3306)/'app'?parseTime=true`; got != want {
+		t.Errorf("expected DSN %q, but got %q", want, got)
+	}
+
+	opts, dsnFunc = sql.MySQLRunOptions([]func(*dockertest.RunOptions){
+		quoted,
+		dockertestx.WithUnquotedEnv("MYSQL_ROOT_PASSWORD", "MYSQL_DATABASE"),
+	})
+	if !slices.Equal(opts.Env, []string{"MYSQL_ROOT_PASSWORD=p@ss=word", "MYSQL_DATABASE=app"}) {
+		t.Errorf("unexpected container env %v", opts.Env)
+	}
+	if got, want := dsnFunc("localhost:3306"), "root:p@ss=word@tcp(localhost:3306)/app?parseTime=true"; got != want {
+		t.Errorf("expected DSN %q, but got %q", want, got)
+	}
+}
@@ -0,0 +1,38 @@
+package sql
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"testing"
+)
+
+// RunMySQLX is like RunMySQL, but it returns the connection as a *sqlx.DB bound to the MySQL driver.
+// For more customization, use RunMySQLXWithOptions.
+func RunMySQLX(t testing.TB) (*sqlx.DB, func()) {
+	return RunMySQLXWithOptions(t, nil)
+}
+
+// RunMySQLXWithOptions is like RunMySQLWithOptions, but it returns the connection as a *sqlx.DB
+// bound to the MySQL driver, so that named queries use the "?" bind type.
+func RunMySQLXWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sqlx.DB, func()) {
+	t.Helper()
+
+	db, cleanup := RunMySQLWithOptions(t, runOpts, hostOpts...)
+	return sqlx.NewDb(db, "mysql"), cleanup
+}
+
+// RunPostgresX is like RunPostgres, but it returns the connection as a *sqlx.DB bound to the PostgreSQL driver.
+// For more customization, use RunPostgresXWithOptions.
+func RunPostgresX(t testing.TB) (*sqlx.DB, func()) {
+	return RunPostgresXWithOptions(t, nil)
+}
+
+// RunPostgresXWithOptions is like RunPostgresWithOptions, but it returns the connection as a *sqlx.DB
+// bound to the PostgreSQL driver, so that named queries and Rebind use the "$1" bind type.
+func RunPostgresXWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*sqlx.DB, func()) {
+	t.Helper()
+
+	db, cleanup := RunPostgresWithOptions(t, runOpts, hostOpts...)
+	return sqlx.NewDb(db, "postgres"), cleanup
+}
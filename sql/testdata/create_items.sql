@@ -0,0 +1,8 @@
+-- Creates and seeds the items table.
+CREATE TABLE items (
+    id INT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+INSERT INTO items (id, name) VALUES (1, 'apple; red');
+INSERT INTO items (id, name) VALUES (2, 'banana');
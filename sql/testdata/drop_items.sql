@@ -0,0 +1,2 @@
+-- Removes the items table.
+DROP TABLE items;
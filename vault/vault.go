@@ -0,0 +1,143 @@
+// Package vault provides helpers to run a HashiCorp Vault server in dev mode and prepare secrets.
+package vault
+
+import (
+	"context"
+	"fmt"
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/vvatanabe/dockertestx/internal"
+	"net"
+	"testing"
+	"time"
+)
+
+const (
+	defaultVaultImage = "hashicorp/vault"
+	defaultVaultTag   = "1.18"
+	defaultVaultPort  = "8200/tcp"
+
+	// DefaultRootToken is the root token of the dev server, unless VAULT_DEV_ROOT_TOKEN_ID is overridden.
+	DefaultRootToken = "root"
+	// kvMount is the path of the KV version 2 secrets engine that dev mode mounts.
+	kvMount = "secret"
+)
+
+// Run starts a Vault Docker container in dev mode using the default settings and returns a
+// *vaultapi.Client authenticated with the root token along with a cleanup function. It uses the
+// default Vault image ("hashicorp/vault") with tag "1.18". For more customization, use RunWithOptions.
+func Run(t testing.TB) (*vaultapi.Client, func()) {
+	return RunWithOptions(t, nil)
+}
+
+// RunWithOptions starts a Vault Docker container in dev mode using Docker and returns a
+// *vaultapi.Client authenticated with the root token along with a cleanup function.
+// It applies the default settings:
+//   - Repository: "hashicorp/vault"
+//   - Tag: "1.18"
+//   - Environment: VAULT_DEV_ROOT_TOKEN_ID=root, VAULT_DEV_LISTEN_ADDRESS=0.0.0.0:8200
+//
+// A dev server is unsealed, keeps its data in memory and mounts a KV version 2 secrets engine at
+// "secret/". The container is considered ready once /v1/sys/health reports an initialized,
+// unsealed server.
+// Additional RunOption functions can be provided via the runOpts parameter to override these defaults,
+// and optional host configuration functions can be provided via hostOpts.
+func RunWithOptions(t testing.TB, runOpts []func(*dockertest.RunOptions), hostOpts ...func(*docker.HostConfig)) (*vaultapi.Client, func()) {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("failed to connect to docker: %s", err)
+	}
+
+	// Set default run options for Vault
+	defaultRunOpts := &dockertest.RunOptions{
+		Repository: defaultVaultImage,
+		Tag:        defaultVaultTag,
+		Env: []string{
+			"VAULT_DEV_ROOT_TOKEN_ID=" + DefaultRootToken,
+			"VAULT_DEV_LISTEN_ADDRESS=0.0.0.0:8200",
+			// Dev mode does not lock memory, so the entrypoint need not grant IPC_LOCK.
+			"SKIP_SETCAP=true",
+		},
+		ExposedPorts: []string{defaultVaultPort},
+	}
+
+	// Apply any provided RunOption functions to override defaults
+	for _, opt := range runOpts {
+		opt(defaultRunOpts)
+	}
+
+	settings := internal.TakeSettings(defaultRunOpts)
+
+	token := internal.GetEnvValue(defaultRunOpts.Env, "VAULT_DEV_ROOT_TOKEN_ID")
+
+	// Pass optional host configuration options
+	resource, err := pool.RunWithOptions(defaultRunOpts, hostOpts...)
+	if err != nil {
+		t.Fatalf("failed to start vault container: %s", err)
+	}
+
+	host, port, err := internal.HostAddress(pool, resource, defaultVaultPort)
+	if err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to get the address of the vault container: %s", err)
+	}
+	actualPort := net.JoinHostPort(host, port)
+	t.Logf("vault container is running on host port '%s'", actualPort)
+
+	if err := settings.OnStart(pool, resource); err != nil {
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to configure vault container: %s", err)
+	}
+	logs := internal.CaptureLogs(t.Logf, pool, resource, settings)
+
+	config := vaultapi.DefaultConfig()
+	config.Address = fmt.Sprintf("http://%s", actualPort)
+	config.Timeout = 5 * time.Second
+	client, err := vaultapi.NewClient(config)
+	if err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("failed to create vault client: %s", err)
+	}
+	client.SetToken(token)
+
+	// Wait until the server is initialized and unsealed
+	if err = settings.Retry(pool, func() error {
+		health, err := client.Sys().Health()
+		if err != nil {
+			return err
+		}
+		if !health.Initialized || health.Sealed {
+			return fmt.Errorf("vault is not ready yet (initialized=%t, sealed=%t)", health.Initialized, health.Sealed)
+		}
+		return nil
+	}); err != nil {
+		logs.Dump()
+		_ = pool.Purge(resource)
+		t.Fatalf("could not connect to vault: %s", err)
+	}
+
+	cleanup := func() {
+		logs.Stop()
+		if err := pool.Purge(resource); err != nil {
+			t.Logf("failed to remove vault container: %s", err)
+		}
+	}
+
+	return client, cleanup
+}
+
+// PrepKV writes data as a new version of the secret at path in the KV version 2 secrets engine
+// that dev mode mounts at "secret/". path is relative to the mount, e.g. "myapp/database".
+// It returns an error if the secret cannot be written.
+func PrepKV(t testing.TB, client *vaultapi.Client, path string, data map[string]interface{}) error {
+	t.Helper()
+
+	if _, err := client.KVv2(kvMount).Put(context.Background(), path, data); err != nil {
+		return fmt.Errorf("failed to write secret '%s': %w", path, err)
+	}
+	return nil
+}
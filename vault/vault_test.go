@@ -0,0 +1,34 @@
+package vault_test
+
+import (
+	"context"
+	"github.com/vvatanabe/dockertestx/vault"
+	"testing"
+)
+
+// TestVault demonstrates writing a secret with PrepKV and reading it back.
+func TestVault(t *testing.T) {
+	client, cleanup := vault.Run(t)
+	defer cleanup()
+
+	data := map[string]interface{}{
+		"username": "app",
+		"password": "s3cr3t",
+	}
+	if err := vault.PrepKV(t, client, "myapp/database", data); err != nil {
+		t.Fatalf("PrepKV failed: %v", err)
+	}
+
+	secret, err := client.KVv2("secret").Get(context.Background(), "myapp/database")
+	if err != nil {
+		t.Fatalf("failed to read secret: %v", err)
+	}
+	for k, want := range data {
+		if got := secret.Data[k]; got != want {
+			t.Errorf("expected %s=%v, got %v", k, want, got)
+		}
+	}
+	if secret.VersionMetadata == nil || secret.VersionMetadata.Version != 1 {
+		t.Errorf("expected version 1, got %+v", secret.VersionMetadata)
+	}
+}
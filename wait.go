@@ -0,0 +1,43 @@
+package dockertestx
+
+import (
+	"fmt"
+	"github.com/ory/dockertest/v3"
+	"net/http"
+	"time"
+)
+
+// httpAttemptTimeout bounds a single request made by WaitForHTTP.
+const httpAttemptTimeout = 5 * time.Second
+
+// WaitForHTTP polls url with GET requests until it responds with wantStatus, using the backoff of
+// pool.Retry. It gives up after timeout and returns the last error in that case. A zero timeout
+// means pool.MaxWait. The pool itself is not modified.
+//
+// It suits services that report readiness through an HTTP endpoint, e.g. as the ReadyFunc of RunContainer:
+//
+//	ReadyFunc: func(hostPort string) error {
+//		return dockertestx.WaitForHTTP(pool, "http://"+hostPort+"/health", http.StatusOK, 0)
+//	}
+func WaitForHTTP(pool *dockertest.Pool, url string, wantStatus int, timeout time.Duration) error {
+	p := *pool
+	if timeout > 0 {
+		p.MaxWait = timeout
+	}
+
+	client := http.Client{Timeout: httpAttemptTimeout}
+	if err := p.Retry(func() error {
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			return fmt.Errorf("unexpected status code from %s: got %d, want %d", url, resp.StatusCode, wantStatus)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("%s did not become ready: %w", url, err)
+	}
+	return nil
+}